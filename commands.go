@@ -0,0 +1,480 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/taigrr/most-specific-period/msp"
+)
+
+// command is a subcommand mapping onto one msp function. run returns the
+// result to write, or nil if it wrote its own output, and the exit code.
+// flags, if set, registers flags specific to the command.
+type command struct {
+	name    string
+	summary string
+	run     func(clock msp.Clock, periods []msp.Period) (result, int)
+	flags   func(fs *flag.FlagSet)
+}
+
+var commands = []command{
+	{"resolve", "print the most specific period at the timestamp", resolve, nil},
+	{"timeline", "print the timeline of most specific periods", timeline, rangeOptions.register},
+	{"changeovers", "print the times at which the most specific period changes", changeovers, rangeOptions.register},
+	{"next", "print the next changeover after the timestamp", next, nil},
+	{"valid", "print the periods containing the timestamp", valid, nil},
+	{"validate", "report malformed periods", validate, nil},
+	{"explain", "explain why the most specific period wins", explain, nil},
+	{"render", "draw the periods and the resolved timeline as a Gantt chart", render, renderOptions.register},
+	{"export", "write the periods and the resolved timeline as an SVG image, HTML page or iCalendar file", export, exportOptions.register},
+	{"serve", "answer queries over HTTP with JSON", serve, serveOptions.register},
+}
+
+// runCommand parses the flags in args, reads the periods from stdin and runs
+// c, writing its result to stdout. It returns the exit code.
+func runCommand(c command, args []string, stdin *os.File, stdout io.Writer) int {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	var o options
+	o.register(fs)
+	if c.flags != nil {
+		c.flags(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitBadInput
+	}
+	o.parsed(fs)
+	clock, err := o.clock()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return exitBadInput
+	}
+	periods, _, err := o.periods(stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return exitBadInput
+	}
+	r, code := c.run(clock, periods)
+	if r == nil {
+		return code
+	}
+	if err := writeResult(stdout, o.output, r); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return exitBadInput
+	}
+	return code
+}
+
+// resolveResult is the most specific period at a timestamp.
+type resolveResult struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Winner is nil if no period contains Timestamp.
+	Winner *periodRecord `json:"winner"`
+}
+
+func resolve(clock msp.Clock, periods []msp.Period) (result, int) {
+	r := resolveResult{Timestamp: clock.Now()}
+	winner, err := msp.MostSpecific(r.Timestamp, periods...)
+	if err != nil {
+		return r, exitNoPeriod
+	}
+	record := toRecord(winner)
+	r.Winner = &record
+	return r, exitOK
+}
+
+func (r resolveResult) text(w io.Writer) {
+	if r.Winner == nil {
+		fmt.Fprintln(w, "No significant period found")
+		return
+	}
+	fmt.Fprintln(w, r.Winner.Identifier)
+}
+
+func (r resolveResult) rows() ([]string, [][]string) {
+	var rows [][]string
+	if r.Winner != nil {
+		rows = append(rows, r.Winner.row())
+	}
+	return []string{"identifier", "start", "end"}, rows
+}
+
+// periodsResult is a list of periods.
+type periodsResult []periodRecord
+
+func timeline(_ msp.Clock, periods []msp.Period) (result, int) {
+	from, to, err := rangeOptions.between()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return nil, exitBadInput
+	}
+	if from.IsZero() {
+		return periodsResult(toRecords(msp.GenerateTimeline(periods...))), exitOK
+	}
+	return periodsResult(toRecords(msp.GenerateTimelineBetween(from, to, periods...))), exitOK
+}
+
+func valid(clock msp.Clock, periods []msp.Period) (result, int) {
+	r := periodsResult(toRecords(msp.ValidTimePeriods(clock.Now(), periods...)))
+	if len(r) == 0 {
+		return r, exitNoPeriod
+	}
+	return r, exitOK
+}
+
+func (r periodsResult) text(w io.Writer) {
+	for _, p := range r {
+		fmt.Fprintln(w, strings.Join(p.row(), "\t"))
+	}
+}
+
+func (r periodsResult) rows() ([]string, [][]string) {
+	var rows [][]string
+	for _, p := range r {
+		rows = append(rows, p.row())
+	}
+	return []string{"identifier", "start", "end"}, rows
+}
+
+// changeoversResult is a list of changeover times.
+type changeoversResult []time.Time
+
+func changeovers(_ msp.Clock, periods []msp.Period) (result, int) {
+	from, to, err := rangeOptions.between()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return nil, exitBadInput
+	}
+	if from.IsZero() {
+		return append(changeoversResult{}, msp.GetChangeOvers(periods...)...), exitOK
+	}
+	return append(changeoversResult{}, msp.GetChangeOversBetween(from, to, periods...)...), exitOK
+}
+
+func (r changeoversResult) text(w io.Writer) {
+	for _, c := range r {
+		fmt.Fprintln(w, c.Format(time.RFC3339Nano))
+	}
+}
+
+func (r changeoversResult) rows() ([]string, [][]string) {
+	var rows [][]string
+	for _, c := range r {
+		rows = append(rows, []string{c.Format(time.RFC3339Nano)})
+	}
+	return []string{"at"}, rows
+}
+
+// nextResult is the first changeover after a timestamp.
+type nextResult struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Changeover is nil if there is none.
+	Changeover *changeoverRecord `json:"changeover"`
+}
+
+// changeoverRecord is an msp.Changeover as written by the structured output
+// formats.
+type changeoverRecord struct {
+	At   time.Time `json:"at"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+}
+
+func next(clock msp.Clock, periods []msp.Period) (result, int) {
+	r := nextResult{Timestamp: clock.Now()}
+	c, err := msp.NextChangeover(clock, periods...)
+	if err != nil {
+		return r, exitNoPeriod
+	}
+	r.Changeover = &changeoverRecord{At: c.At, From: c.From, To: c.To}
+	return r, exitOK
+}
+
+func (r nextResult) text(w io.Writer) {
+	if r.Changeover == nil {
+		fmt.Fprintln(w, "No next changeover found")
+		return
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\n", r.Changeover.At.Format(time.RFC3339Nano), r.Changeover.From, r.Changeover.To)
+}
+
+func (r nextResult) rows() ([]string, [][]string) {
+	var rows [][]string
+	if c := r.Changeover; c != nil {
+		rows = append(rows, []string{c.At.Format(time.RFC3339Nano), c.From, c.To})
+	}
+	return []string{"at", "from", "to"}, rows
+}
+
+// validateResult lists the problems found by msp.Validate.
+type validateResult []problemRecord
+
+// problemRecord is an msp.ValidationError as written by the structured
+// output formats.
+type problemRecord struct {
+	Index      int    `json:"index"`
+	Identifier string `json:"identifier"`
+	Problem    string `json:"problem"`
+}
+
+func validate(_ msp.Clock, periods []msp.Period) (result, int) {
+	r := validateResult{}
+	err := msp.Validate(periods...)
+	if err == nil {
+		return r, exitOK
+	}
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var v *msp.ValidationError
+		if errors.As(e, &v) {
+			r = append(r, problemRecord{
+				Index:      v.Index,
+				Identifier: v.Identifier,
+				Problem:    strings.TrimPrefix(v.Err.Error(), "error: "),
+			})
+		}
+	}
+	return r, exitBadInput
+}
+
+func (r validateResult) text(w io.Writer) {
+	for _, p := range r {
+		fmt.Fprintf(w, "period %d %q: %s\n", p.Index, p.Identifier, p.Problem)
+	}
+}
+
+func (r validateResult) rows() ([]string, [][]string) {
+	var rows [][]string
+	for _, p := range r {
+		rows = append(rows, []string{strconv.Itoa(p.Index), p.Identifier, p.Problem})
+	}
+	return []string{"index", "identifier", "problem"}, rows
+}
+
+// explainResult is an msp.Explanation as written by the structured output
+// formats.
+type explainResult struct {
+	Timestamp  time.Time         `json:"timestamp"`
+	Winner     *periodRecord     `json:"winner"`
+	DecidedBy  string            `json:"decided_by"`
+	Candidates []candidateRecord `json:"candidates"`
+	Excluded   []exclusionRecord `json:"excluded"`
+
+	explanation msp.Explanation
+}
+
+// candidateRecord is an msp.Candidate as written by the structured output
+// formats.
+type candidateRecord struct {
+	periodRecord
+	Index    int `json:"index"`
+	Priority int `json:"priority"`
+}
+
+// exclusionRecord is an msp.Exclusion as written by the structured output
+// formats.
+type exclusionRecord struct {
+	periodRecord
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+func explain(clock msp.Clock, periods []msp.Period) (result, int) {
+	e := msp.Explain(clock.Now(), periods...)
+	r := explainResult{
+		Timestamp:   e.Timestamp,
+		DecidedBy:   e.DecidedBy,
+		Candidates:  []candidateRecord{},
+		Excluded:    []exclusionRecord{},
+		explanation: e,
+	}
+	for _, c := range e.Candidates {
+		r.Candidates = append(r.Candidates, candidateRecord{periodRecord: toRecord(c.Period), Index: c.Index, Priority: c.Priority})
+	}
+	for _, x := range e.Excluded {
+		r.Excluded = append(r.Excluded, exclusionRecord{periodRecord: toRecord(x.Period), Index: x.Index, Reason: x.Reason.String()})
+	}
+	if len(r.Candidates) == 0 {
+		return r, exitNoPeriod
+	}
+	r.Winner = &r.Candidates[0].periodRecord
+	return r, exitOK
+}
+
+func (r explainResult) text(w io.Writer) {
+	fmt.Fprint(w, r.explanation)
+}
+
+// rows lists the candidates from most to least specific, followed by the
+// excluded periods.
+func (r explainResult) rows() ([]string, [][]string) {
+	var rows [][]string
+	for i, c := range r.Candidates {
+		rows = append(rows, append([]string{"candidate", strconv.Itoa(i + 1)}, append(c.row(), "")...))
+	}
+	for _, x := range r.Excluded {
+		rows = append(rows, append([]string{"excluded", ""}, append(x.row(), x.Reason)...))
+	}
+	return []string{"record", "rank", "identifier", "start", "end", "reason"}, rows
+}
+
+// rangeFlags are the -from and -to flags limiting a command to a range.
+type rangeFlags struct {
+	from string
+	to   string
+}
+
+var rangeOptions rangeFlags
+
+// register adds the range flags to fs.
+func (f *rangeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.from, "from", "", "RFC 3339 start of the range, requires -to")
+	fs.StringVar(&f.to, "to", "", "RFC 3339 end of the range, requires -from")
+}
+
+// between parses the -from and -to flags, which must be set together. Both
+// are zero if neither is set.
+func (f *rangeFlags) between() (from, to time.Time, err error) {
+	if (f.from == "") != (f.to == "") {
+		return from, to, errors.New("-from and -to must be set together")
+	}
+	return f.axis()
+}
+
+// renderFlags are the flags of the render command.
+type renderFlags struct {
+	rangeFlags
+	width int
+	color bool
+}
+
+var renderOptions renderFlags
+
+// register adds the render flags to fs.
+func (f *renderFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.width, "width", 60, "number of columns of the time axis")
+	fs.StringVar(&f.from, "from", "", "RFC 3339 start of the time axis, defaults to the earliest start")
+	fs.StringVar(&f.to, "to", "", "RFC 3339 end of the time axis, defaults to the latest end")
+	fs.BoolVar(&f.color, "color", false, "highlight the timeline row with terminal colors")
+}
+
+// axis parses the -from and -to flags. Unset flags leave the zero time.
+func (f *rangeFlags) axis() (from, to time.Time, err error) {
+	for _, flag := range []struct {
+		value string
+		t     *time.Time
+	}{{f.from, &from}, {f.to, &to}} {
+		if flag.value == "" {
+			continue
+		}
+		if *flag.t, err = time.Parse(time.RFC3339, flag.value); err != nil {
+			return from, to, fmt.Errorf("invalid time %q", flag.value)
+		}
+	}
+	return from, to, nil
+}
+
+// renderResult is a rendered chart.
+type renderResult struct {
+	Chart string `json:"chart"`
+}
+
+func render(_ msp.Clock, periods []msp.Period) (result, int) {
+	opts := msp.GanttOptions{Width: renderOptions.width, ANSI: renderOptions.color}
+	var err error
+	if opts.From, opts.To, err = renderOptions.axis(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return nil, exitBadInput
+	}
+	var b strings.Builder
+	msp.RenderGantt(&b, opts, periods...)
+	return renderResult{Chart: b.String()}, exitOK
+}
+
+func (r renderResult) text(w io.Writer) {
+	fmt.Fprint(w, r.Chart)
+}
+
+func (r renderResult) rows() ([]string, [][]string) {
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimSuffix(r.Chart, "\n"), "\n") {
+		if line != "" {
+			rows = append(rows, []string{line})
+		}
+	}
+	return []string{"chart"}, rows
+}
+
+// exportFlags are the flags of the export command.
+type exportFlags struct {
+	renderFlags
+	format  string
+	title   string
+	now     bool
+	periods bool
+}
+
+var exportOptions exportFlags
+
+// register adds the export flags to fs.
+func (f *exportFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.width, "width", 800, "width of the image in pixels")
+	fs.StringVar(&f.from, "from", "", "RFC 3339 start of the time axis, defaults to the earliest start")
+	fs.StringVar(&f.to, "to", "", "RFC 3339 end of the time axis, defaults to the latest end")
+	fs.StringVar(&f.format, "format", "svg", "document format: svg, html or ics")
+	fs.StringVar(&f.title, "title", "", "title of the image, page or calendar")
+	fs.BoolVar(&f.now, "now", false, "draw a cursor at the timestamp given by -d, or now")
+	fs.BoolVar(&f.periods, "periods", false, "add the input periods to the calendar next to the timeline")
+}
+
+// exportResult is an exported document.
+type exportResult struct {
+	Format   string `json:"format"`
+	Document string `json:"document"`
+}
+
+func export(clock msp.Clock, periods []msp.Period) (result, int) {
+	opts := msp.SVGOptions{Width: exportOptions.width, Title: exportOptions.title}
+	var err error
+	if opts.From, opts.To, err = exportOptions.axis(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return nil, exitBadInput
+	}
+	if exportOptions.now {
+		opts.Now = clock.Now()
+	}
+	var b strings.Builder
+	switch exportOptions.format {
+	case "svg":
+		msp.RenderSVG(&b, opts, periods...)
+	case "html":
+		msp.RenderHTML(&b, opts, periods...)
+	case "ics":
+		msp.WriteICalendar(&b, msp.ICalendarExportOptions{
+			From:    opts.From,
+			To:      opts.To,
+			Periods: exportOptions.periods,
+			Name:    exportOptions.title,
+			Stamp:   clock.Now(),
+		}, periods...)
+	default:
+		fmt.Fprintf(os.Stderr, "ERROR: unknown document format %q, expected svg, html or ics\n", exportOptions.format)
+		return nil, exitBadInput
+	}
+	return exportResult{Format: exportOptions.format, Document: b.String()}, exitOK
+}
+
+func (r exportResult) text(w io.Writer) {
+	fmt.Fprint(w, r.Document)
+}
+
+func (r exportResult) rows() ([]string, [][]string) {
+	return []string{"format", "document"}, [][]string{{r.Format, r.Document}}
+}
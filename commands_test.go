@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taigrr/most-specific-period/msp"
+)
+
+func TestCommandErrorsWriteNothing(t *testing.T) {
+	defer func(r renderFlags, e exportFlags) {
+		renderOptions, exportOptions = r, e
+	}(renderOptions, exportOptions)
+	testCases := []struct {
+		testID string
+		setup  func()
+		run    func(msp.Clock, []msp.Period) (result, int)
+	}{
+		{
+			testID: "Render with an invalid range",
+			setup:  func() { renderOptions = renderFlags{rangeFlags: rangeFlags{from: "nope"}} },
+			run:    render,
+		},
+		{
+			testID: "Export with an invalid range",
+			setup: func() {
+				exportOptions = exportFlags{format: "svg", renderFlags: renderFlags{rangeFlags: rangeFlags{to: "nope"}}}
+			},
+			run: export,
+		},
+		{
+			testID: "Export to an unknown format",
+			setup:  func() { exportOptions = exportFlags{format: "pdf"} },
+			run:    export,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			tc.setup()
+			r, code := tc.run(msp.RealClock{}, nil)
+			if r != nil {
+				t.Errorf("Result %#v does not match expected nil", r)
+			}
+			if code != exitBadInput {
+				t.Errorf("Exit code %d does not match expected %d", code, exitBadInput)
+			}
+		})
+	}
+}
+
+func TestCommands(t *testing.T) {
+	defer func(r rangeFlags) { rangeOptions = r }(rangeOptions)
+	input := fixture + "broken\n2024-10-01T00:00:00Z\n2024-09-20T00:00:00Z\n"
+	periods, err := readLines(strings.NewReader(input), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+	from := time.Date(2024, time.June, 20, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, time.October, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		testID   string
+		command  string
+		args     []string
+		code     int
+		expected any
+	}{
+		{
+			testID:  "Resolve",
+			command: "resolve",
+			code:    exitOK,
+			expected: func() any {
+				winner, err := msp.MostSpecific(ts, periods...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				record := toRecord(winner)
+				return resolveResult{Timestamp: ts, Winner: &record}
+			}(),
+		},
+		{
+			testID:   "Timeline",
+			command:  "timeline",
+			code:     exitOK,
+			expected: periodsResult(toRecords(msp.GenerateTimeline(periods...))),
+		},
+		{
+			testID:   "Timeline between",
+			command:  "timeline",
+			args:     []string{"-from", from.Format(time.RFC3339), "-to", to.Format(time.RFC3339)},
+			code:     exitOK,
+			expected: periodsResult(toRecords(msp.GenerateTimelineBetween(from, to, periods...))),
+		},
+		{
+			testID:  "Timeline with only -from",
+			command: "timeline",
+			args:    []string{"-from", from.Format(time.RFC3339)},
+			code:    exitBadInput,
+		},
+		{
+			testID:   "Changeovers",
+			command:  "changeovers",
+			code:     exitOK,
+			expected: changeoversResult(msp.GetChangeOvers(periods...)),
+		},
+		{
+			testID:   "Changeovers between",
+			command:  "changeovers",
+			args:     []string{"-from", from.Format(time.RFC3339), "-to", to.Format(time.RFC3339)},
+			code:     exitOK,
+			expected: changeoversResult(msp.GetChangeOversBetween(from, to, periods...)),
+		},
+		{
+			testID:  "Changeovers with only -to",
+			command: "changeovers",
+			args:    []string{"-to", to.Format(time.RFC3339)},
+			code:    exitBadInput,
+		},
+		{
+			testID:  "Next",
+			command: "next",
+			code:    exitOK,
+			expected: func() any {
+				c, err := msp.NextChangeover(msp.NewFakeClock(ts), periods...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return nextResult{Timestamp: ts, Changeover: &changeoverRecord{At: c.At, From: c.From, To: c.To}}
+			}(),
+		},
+		{
+			testID:   "Valid",
+			command:  "valid",
+			code:     exitOK,
+			expected: periodsResult(toRecords(msp.ValidTimePeriods(ts, periods...))),
+		},
+		{
+			testID:  "Validate",
+			command: "validate",
+			code:    exitBadInput,
+			expected: func() any {
+				r := validateResult{}
+				for _, e := range msp.Validate(periods...).(interface{ Unwrap() []error }).Unwrap() {
+					var v *msp.ValidationError
+					if errors.As(e, &v) {
+						r = append(r, problemRecord{Index: v.Index, Identifier: v.Identifier, Problem: strings.TrimPrefix(v.Err.Error(), "error: ")})
+					}
+				}
+				if len(r) != 1 || r[0].Identifier != "broken" {
+					t.Fatalf("Fixture problems %v do not match expected broken", r)
+				}
+				return r
+			}(),
+		},
+		{
+			testID:  "Explain",
+			command: "explain",
+			code:    exitOK,
+			expected: func() any {
+				e := msp.Explain(ts, periods...)
+				r := explainResult{Timestamp: ts, DecidedBy: e.DecidedBy, Candidates: []candidateRecord{}, Excluded: []exclusionRecord{}}
+				for _, c := range e.Candidates {
+					r.Candidates = append(r.Candidates, candidateRecord{periodRecord: toRecord(c.Period), Index: c.Index, Priority: c.Priority})
+				}
+				for _, x := range e.Excluded {
+					r.Excluded = append(r.Excluded, exclusionRecord{periodRecord: toRecord(x.Period), Index: x.Index, Reason: x.Reason.String()})
+				}
+				r.Winner = &r.Candidates[0].periodRecord
+				return r
+			}(),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			rangeOptions = rangeFlags{}
+			args := append([]string{"-d", ts.Format(time.RFC3339), "-output", "json"}, tc.args...)
+			out, code := runCLI(t, tc.command, input, args...)
+			if code != tc.code {
+				t.Errorf("Exit code %d does not match expected %d", code, tc.code)
+			}
+			if tc.expected == nil {
+				if out != "" {
+					t.Errorf("Unexpected output %q", out)
+				}
+				return
+			}
+			got := reflect.New(reflect.TypeOf(tc.expected))
+			if err := json.Unmarshal([]byte(out), got.Interface()); err != nil {
+				t.Fatalf("Invalid JSON: %v", err)
+			}
+			if !reflect.DeepEqual(got.Elem().Interface(), tc.expected) {
+				t.Errorf("Result %+v does not match expected %+v", got.Elem().Interface(), tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/taigrr/most-specific-period/msp"
+	"gopkg.in/yaml.v3"
+)
+
+// inputFormats lists the values accepted by -input-format.
+var inputFormats = []string{"lines", "json", "ndjson", "yaml", "csv", "ics"}
+
+// fieldNames are the names of the identifier, start and end fields of a
+// record in the structured input formats.
+type fieldNames struct {
+	Identifier string
+	Start      string
+	End        string
+	// IdentifierSet reports whether Identifier was given with -id-field
+	// rather than left at its default.
+	IdentifierSet bool
+}
+
+// readPeriods reads periods from r in the given format. For the lines
+// format, prompt asks for each field as it is read.
+func readPeriods(r io.Reader, format string, fields fieldNames, prompt bool) ([]msp.Period, error) {
+	switch format {
+	case "lines":
+		return readLines(r, prompt)
+	case "json":
+		var records []map[string]any
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		if err := dec.Decode(&records); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return fromRecords(records, fields)
+	case "ndjson":
+		var records []map[string]any
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		for {
+			var record map[string]any
+			err := dec.Decode(&record)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON in record %d: %w", len(records)+1, err)
+			}
+			records = append(records, record)
+		}
+		return fromRecords(records, fields)
+	case "yaml":
+		var records []map[string]any
+		if err := yaml.NewDecoder(r).Decode(&records); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		return fromRecords(records, fields)
+	case "csv":
+		return readCSV(r, fields)
+	case "ics":
+		return readICS(r, fields)
+	}
+	return nil, fmt.Errorf("unknown input format %q, expected one of %s", format, strings.Join(inputFormats, ", "))
+}
+
+// readICS reads the events of an iCalendar file. Events are identified by
+// their SUMMARY unless -id-field names another property, such as UID.
+func readICS(r io.Reader, fields fieldNames) ([]msp.Period, error) {
+	var opts msp.ICalendarOptions
+	if fields.IdentifierSet {
+		opts.IdentifierProperty = fields.Identifier
+	}
+	return msp.ParseICalendar(r, opts)
+}
+
+// readLines reads the original format of three lines per period: the
+// identifier, the start time and the end time.
+func readLines(r io.Reader, prompt bool) ([]msp.Period, error) {
+	s := bufio.NewScanner(r)
+	count := 1
+
+	if prompt {
+		fmt.Print("Identifier: ")
+	}
+
+	periods := []msp.Period{}
+	currentPeriod := Period{}
+	for s.Scan() {
+		input := s.Text()
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		if count%3 == 0 {
+			t, err := time.Parse(time.RFC3339, input)
+			if err != nil {
+				return nil, fmt.Errorf("period %d: invalid end time %q", len(periods)+1, input)
+			}
+			currentPeriod.EndTime = t
+
+			periods = append(periods, currentPeriod)
+			if prompt {
+				fmt.Print("Identifier: ")
+			}
+		}
+		if count%3 == 1 {
+			currentPeriod = Period{Identifier: s.Text()}
+			if prompt {
+				fmt.Print("StartTime: ")
+			}
+		}
+		if count%3 == 2 {
+			t, err := time.Parse(time.RFC3339, input)
+			if err != nil {
+				return nil, fmt.Errorf("period %d: invalid start time %q", len(periods)+1, input)
+			}
+			currentPeriod.StartTime = t
+
+			if prompt {
+				fmt.Print("EndTime: ")
+			}
+		}
+		count++
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if count%3 != 1 {
+		return nil, fmt.Errorf("period %d: incomplete, expected an identifier, a start time and an end time", len(periods)+1)
+	}
+	return periods, nil
+}
+
+// readCSV reads one period per row. If the first row names the configured
+// fields it is taken as a header and columns are matched by name; otherwise
+// the columns are identifier, start and end.
+func readCSV(r io.Reader, fields fieldNames) ([]msp.Period, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns := []string{fields.Identifier, fields.Start, fields.End}
+	if len(rows) > 0 && isHeader(rows[0], fields) {
+		columns = rows[0]
+		rows = rows[1:]
+	}
+	var records []map[string]any
+	for _, row := range rows {
+		record := map[string]any{}
+		for i, value := range row {
+			if i >= len(columns) {
+				break
+			}
+			// an empty identifier is missing, while an empty time
+			// leaves the period unbounded
+			if value != "" || columns[i] != fields.Identifier {
+				record[columns[i]] = value
+			}
+		}
+		records = append(records, record)
+	}
+	return fromRecords(records, fields)
+}
+
+// isHeader reports whether row contains the configured identifier field.
+func isHeader(row []string, fields fieldNames) bool {
+	for _, name := range row {
+		if strings.TrimSpace(name) == fields.Identifier {
+			return true
+		}
+	}
+	return false
+}
+
+// fromRecords converts decoded records into periods. A null or empty start
+// or end time leaves the period unbounded on that side, while a missing one
+// is an error so that misspelled field names do not go unnoticed.
+func fromRecords(records []map[string]any, fields fieldNames) ([]msp.Period, error) {
+	periods := []msp.Period{}
+	for i, record := range records {
+		p, err := fromRecord(record, fields)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+		periods = append(periods, p)
+	}
+	return periods, nil
+}
+
+// fromRecord converts a single decoded record into a period.
+func fromRecord(record map[string]any, fields fieldNames) (Period, error) {
+	var p Period
+	switch id := record[fields.Identifier].(type) {
+	case nil:
+		return p, fmt.Errorf("missing field %q", fields.Identifier)
+	case string:
+		p.Identifier = id
+	default:
+		p.Identifier = fmt.Sprint(id)
+	}
+	var err error
+	if p.StartTime, err = timeField(record, fields.Start); err != nil {
+		return p, err
+	}
+	if p.EndTime, err = timeField(record, fields.End); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// timeField returns the RFC 3339 timestamp in the named field, or the zero
+// time if the field is null or empty. A missing field is an error.
+func timeField(record map[string]any, name string) (time.Time, error) {
+	value, ok := record[name]
+	if !ok {
+		return time.Time{}, fmt.Errorf("missing field %q, use null or an empty value for an unbounded time", name)
+	}
+	switch v := value.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case string:
+		if v == "" {
+			return time.Time{}, nil
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q in field %q", v, name)
+		}
+		return t, nil
+	default:
+		return time.Time{}, fmt.Errorf("field %q is not a timestamp: %v", name, v)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadPeriodsFields(t *testing.T) {
+	start := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	fields := fieldNames{Identifier: "identifier", Start: "start", End: "end"}
+	testCases := []struct {
+		testID string
+		format string
+		input  string
+		fields fieldNames
+		start  time.Time
+		err    string
+	}{
+		{
+			testID: "Null end",
+			format: "json",
+			input:  `[{"identifier": "a", "start": "2024-06-01T00:00:00Z", "end": null}]`,
+			fields: fields,
+			start:  start,
+		},
+		{
+			testID: "Empty start",
+			format: "json",
+			input:  `[{"identifier": "a", "start": "", "end": null}]`,
+			fields: fields,
+		},
+		{
+			testID: "Missing end",
+			format: "json",
+			input:  `[{"identifier": "a", "start": "2024-06-01T00:00:00Z"}]`,
+			fields: fields,
+			err:    `record 1: missing field "end"`,
+		},
+		{
+			testID: "Misspelled start field",
+			format: "json",
+			input:  `[{"identifier": "a", "start": "2024-06-01T00:00:00Z", "end": null}]`,
+			fields: fieldNames{Identifier: "identifier", Start: "begin", End: "end"},
+			err:    `record 1: missing field "begin"`,
+		},
+		{
+			testID: "YAML null end",
+			format: "yaml",
+			input:  "- identifier: a\n  start: 2024-06-01T00:00:00Z\n  end:\n",
+			fields: fields,
+			start:  start,
+		},
+		{
+			testID: "CSV empty end",
+			format: "csv",
+			input:  "identifier,start,end\na,2024-06-01T00:00:00Z,\n",
+			fields: fields,
+			start:  start,
+		},
+		{
+			testID: "CSV header without end",
+			format: "csv",
+			input:  "identifier,start,stop\na,2024-06-01T00:00:00Z,\n",
+			fields: fields,
+			err:    `record 1: missing field "end"`,
+		},
+		{
+			testID: "CSV empty identifier",
+			format: "csv",
+			input:  ",2024-06-01T00:00:00Z,\n",
+			fields: fields,
+			err:    `record 1: missing field "identifier"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			periods, err := readPeriods(strings.NewReader(tc.input), tc.format, tc.fields, false)
+			if tc.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+					t.Fatalf("Error %v does not match expected %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(periods) != 1 {
+				t.Fatalf("Expected 1 period but got %d", len(periods))
+			}
+			if got := periods[0].GetStartTime(); !got.Equal(tc.start) {
+				t.Errorf("Start %v does not match expected %v", got, tc.start)
+			}
+			if got := periods[0].GetEndTime(); !got.IsZero() {
+				t.Errorf("End %v does not match expected unbounded end", got)
+			}
+		})
+	}
+}
+
+func TestReadICSIdentifier(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:standup-uid",
+		"SUMMARY:standup",
+		"IDENTIFIER:custom",
+		"DTSTART:20240603T090000Z",
+		"DTEND:20240603T100000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	testCases := []struct {
+		testID string
+		fields fieldNames
+		result string
+	}{
+		{
+			testID: "Default is SUMMARY",
+			fields: fieldNames{Identifier: "identifier", Start: "start", End: "end"},
+			result: "standup",
+		},
+		{
+			testID: "UID",
+			fields: fieldNames{Identifier: "UID", Start: "start", End: "end", IdentifierSet: true},
+			result: "standup-uid",
+		},
+		{
+			testID: "Explicit field named like the default",
+			fields: fieldNames{Identifier: "identifier", Start: "start", End: "end", IdentifierSet: true},
+			result: "custom",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			periods, err := readPeriods(strings.NewReader(input), "ics", tc.fields, false)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(periods) != 1 || periods[0].GetIdentifier() != tc.result {
+				t.Errorf("Periods %v do not match expected %q", periods, tc.result)
+			}
+		})
+	}
+}
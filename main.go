@@ -1,10 +1,10 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -41,85 +41,106 @@ func warnMessage() {
 }
 
 func helpMessage() {
-	fmt.Print("\nmost-specific-period [-h][-d]\n\nGenerates a timeline of periods and will provide a most specific period if available.\n\n-h\tShows this help menu\n-d\tProvide an RFC 3339 time to provide an alternate point for calculating MSP.")
+	fmt.Print("\nmost-specific-period [command] [-h][-d][-input-format][-id-field][-start-field][-end-field][-output]\n\nGenerates a timeline of periods and will provide a most specific period if available.\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Printf("  %s\t%s\n", c.name, c.summary)
+	}
+	fmt.Print("\nWithout a command, the timeline and the most specific period are printed.\n\n-h\tShows this help menu\n-d\tProvide an RFC 3339 time to provide an alternate point for calculating MSP.\n-input-format\tRead periods as lines (default), json, ndjson, yaml, csv or ics.\n-id-field, -start-field, -end-field\tName the fields holding the identifier, start and end time in structured input.\n-output\tWrite text (default), json, csv or table.\n\nExits with 1 if no period contains the timestamp and 2 if the input is invalid.\n")
 }
 
-func main() {
-	var start time.Time
-	help := flag.Bool("h", false, "displays help command")
-	userDate := flag.String("d", "", "use a custom date to calculate MSP")
-	flag.Parse()
-	if *help {
-		helpMessage()
-		os.Exit(0)
-	}
+// options are the flags shared by every command.
+type options struct {
+	date        string
+	inputFormat string
+	fields      fieldNames
+	output      string
+}
+
+// register adds the shared flags to fs.
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.date, "d", "", "use a custom date to calculate MSP")
+	fs.StringVar(&o.inputFormat, "input-format", "lines", "input format: "+strings.Join(inputFormats, ", "))
+	fs.StringVar(&o.fields.Identifier, "id-field", "identifier", "name of the identifier field in structured input")
+	fs.StringVar(&o.fields.Start, "start-field", "start", "name of the start time field in structured input")
+	fs.StringVar(&o.fields.End, "end-field", "end", "name of the end time field in structured input")
+	fs.StringVar(&o.output, "output", "text", "output format: "+strings.Join(outputFormats, ", "))
+}
 
-	if userDate != nil && *userDate != "" {
-		t, err := time.Parse(time.RFC3339, *userDate)
-		if err != nil {
-			fmt.Println("Please enter the date using the YYYY-MM-DDT00:00:00.00Z")
-			os.Exit(1)
+// parsed records which of the shared flags were set once fs is parsed.
+func (o *options) parsed(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "id-field" {
+			o.fields.IdentifierSet = true
 		}
-		start = t
-	} else {
-		start = time.Now()
+	})
+}
+
+// clock returns the clock to query: the real one, or one pinned to -d.
+func (o *options) clock() (msp.Clock, error) {
+	if o.date == "" {
+		return msp.RealClock{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, o.date)
+	if err != nil {
+		return nil, fmt.Errorf("please enter the date using the YYYY-MM-DDT00:00:00.00Z format")
+	}
+	return msp.NewFakeClock(t), nil
+}
+
+// periods reads the periods from stdin, prompting for them if stdin is a
+// terminal and the output is meant for humans. It reports whether it
+// prompted.
+func (o *options) periods(stdin *os.File) ([]msp.Period, bool, error) {
+	if !slices.Contains(outputFormats, o.output) {
+		return nil, false, fmt.Errorf("unknown output format %q, expected one of %s", o.output, strings.Join(outputFormats, ", "))
 	}
 	terminal := false
-	fi, _ := os.Stdin.Stat()
+	fi, _ := stdin.Stat()
 	if (fi.Mode() & os.ModeCharDevice) == 0 {
 		// this is a file being read in, no need to print the prompt just yet
-	} else {
+	} else if o.inputFormat == "lines" && o.output == "text" {
 		// this is a terminal, let's help the user out
 		terminal = true
 		warnMessage()
 	}
-	s := bufio.NewScanner(os.Stdin)
-	count := 1
+	periods, err := readPeriods(stdin, o.inputFormat, o.fields, terminal)
+	return periods, terminal, err
+}
 
-	if terminal {
-		fmt.Print("Identifier: ")
+func main() {
+	if len(os.Args) > 1 {
+		if i := slices.IndexFunc(commands, func(c command) bool { return c.name == os.Args[1] }); i >= 0 {
+			os.Exit(runCommand(commands[i], os.Args[2:], os.Stdin, os.Stdout))
+		}
 	}
 
-	periods := []msp.Period{}
-	currentPeriod := Period{}
-	for s.Scan() {
-		input := s.Text()
-		input = strings.TrimSpace(input)
-		if input == "" {
-			continue
-		}
-		if count%3 == 0 {
-			t, err := time.Parse(time.RFC3339, input)
-			if err != nil {
-				fmt.Printf("ERROR: Invalid timestamp: %v", t)
-				os.Exit(1)
-			}
-			currentPeriod.EndTime = t
-
-			periods = append(periods, currentPeriod)
-			if terminal {
-				fmt.Print("Identifier: ")
-			}
-		}
-		if count%3 == 1 {
-			currentPeriod = Period{Identifier: s.Text()}
-			if terminal {
-				fmt.Print("StartTime: ")
-			}
-		}
-		if count%3 == 2 {
-			t, err := time.Parse(time.RFC3339, input)
-			if err != nil {
-				fmt.Printf("ERROR: Invalid timestamp: %v", t)
-				os.Exit(1)
-			}
-			currentPeriod.StartTime = t
-
-			if terminal {
-				fmt.Print("EndTime: ")
-			}
-		}
-		count++
+	var o options
+	help := flag.Bool("h", false, "displays help command")
+	o.register(flag.CommandLine)
+	flag.Parse()
+	o.parsed(flag.CommandLine)
+	if *help {
+		helpMessage()
+		os.Exit(exitOK)
+	}
+	if flag.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: unknown command %q\n", flag.Arg(0))
+		os.Exit(exitBadInput)
+	}
+
+	clock, err := o.clock()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Please enter the date using the YYYY-MM-DDT00:00:00.00Z")
+		os.Exit(exitBadInput)
+	}
+	periods, terminal, err := o.periods(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(exitBadInput)
+	}
+
+	if o.output != "text" {
+		os.Exit(writeReport(os.Stdout, o.output, clock.Now(), periods))
 	}
 
 	vals := msp.GenerateTimeline(periods...)
@@ -127,10 +148,10 @@ func main() {
 	for _, val := range vals {
 		fmt.Println(val)
 	}
-	m, err := msp.MostSpecificPeriod(start, periods...)
+	m, err := msp.Current(clock, periods...)
 	if err != nil {
 		fmt.Printf("No significant period found\n")
-		os.Exit(1)
+		os.Exit(exitNoPeriod)
 	}
 	if terminal {
 		fmt.Printf("\nThe MSP from the list was: ")
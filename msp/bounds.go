@@ -0,0 +1,145 @@
+package msp
+
+import (
+	"fmt"
+	"time"
+)
+
+// Bounds says whether a period contains its start and end times.
+type Bounds int
+
+const (
+	// ClosedOpen contains the start time but not the end time, [start, end).
+	// It is the default for periods that do not implement Bounded.
+	ClosedOpen Bounds = iota
+	// Closed contains both the start and the end time, [start, end].
+	Closed
+	// Open contains neither the start nor the end time, (start, end).
+	Open
+	// OpenClosed contains the end time but not the start time, (start, end].
+	OpenClosed
+)
+
+// String returns the interval notation for the bounds.
+func (b Bounds) String() string {
+	switch b {
+	case ClosedOpen:
+		return "[start, end)"
+	case Closed:
+		return "[start, end]"
+	case Open:
+		return "(start, end)"
+	case OpenClosed:
+		return "(start, end]"
+	}
+	return fmt.Sprintf("Bounds(%d)", int(b))
+}
+
+// Bounded is implemented by periods whose bounds differ from the default
+// ClosedOpen.
+type Bounded interface {
+	GetBounds() Bounds
+}
+
+// GetBounds returns p's bounds if it implements Bounded, or ClosedOpen.
+func GetBounds(p Period) Bounds {
+	if x, ok := p.(Bounded); ok {
+		return x.GetBounds()
+	}
+	return ClosedOpen
+}
+
+// WithBounds returns periods with their bounds replaced by b, for inputs
+// that do not carry their own. The returned periods wrap the originals, so
+// custom comparators relying on type assertions should set the Bounds field
+// of TimeWindow or RecurringPeriod instead.
+func WithBounds(b Bounds, periods ...Period) []Period {
+	out := make([]Period, len(periods))
+	for i, p := range periods {
+		wrapped := boundedPeriod{Period: p, bounds: b}
+		if _, ok := p.(Recurring); ok {
+			out[i] = boundedRecurring{wrapped}
+			continue
+		}
+		out[i] = wrapped
+	}
+	return out
+}
+
+// boundedPeriod overrides the bounds of a period.
+type boundedPeriod struct {
+	Period
+	bounds Bounds
+}
+
+// GetBounds returns the overriding bounds.
+func (p boundedPeriod) GetBounds() Bounds {
+	return p.bounds
+}
+
+// GetPriority returns the priority of the wrapped period.
+func (p boundedPeriod) GetPriority() int {
+	return GetPriority(p.Period)
+}
+
+// GetGranularity returns the granularity of the wrapped period.
+func (p boundedPeriod) GetGranularity() Granularity {
+	return GetGranularity(p.Period)
+}
+
+// boundedRecurring overrides the bounds of a Recurring period and each of
+// its occurrences.
+type boundedRecurring struct {
+	boundedPeriod
+}
+
+// Occurrences returns the occurrences of the wrapped period that overlap
+// [from, to) under the overriding bounds.
+func (p boundedRecurring) Occurrences(from, to time.Time) []Period {
+	var out []Period
+	// a closed end reaches one instant further, so ask for occurrences
+	// ending right at from as well
+	for _, o := range p.Period.(Recurring).Occurrences(from.Add(-time.Nanosecond), to) {
+		o = boundedPeriod{Period: o, bounds: p.bounds}
+		if overlaps(o, from, to) {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// startOf returns the first instant contained in p, or the zero time if p is
+// unbounded towards the past. Timestamps have nanosecond resolution, so an
+// open start is the same as a closed start one nanosecond later.
+func startOf(p Period) time.Time {
+	start := p.GetStartTime()
+	if start.IsZero() {
+		return start
+	}
+	if b := GetBounds(p); b == Open || b == OpenClosed {
+		return start.Add(time.Nanosecond)
+	}
+	return start
+}
+
+// endOf returns the first instant after p, or the zero time if p is
+// unbounded towards the future. Like startOf, a closed end is the same as an
+// open end one nanosecond later.
+func endOf(p Period) time.Time {
+	end := p.GetEndTime()
+	if end.IsZero() {
+		return end
+	}
+	if b := GetBounds(p); b == Closed || b == OpenClosed {
+		return end.Add(time.Nanosecond)
+	}
+	return end
+}
+
+// overlaps reports whether p contains any instant in [from, to).
+func overlaps(p Period, from, to time.Time) bool {
+	start := startOf(p)
+	end := endOf(p)
+	return (start.IsZero() || start.Before(to)) && (end.IsZero() || end.After(from)) &&
+		(start.IsZero() || end.IsZero() || start.Before(end))
+}
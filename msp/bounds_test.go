@@ -0,0 +1,167 @@
+package msp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMostSpecificPeriodBounds(t *testing.T) {
+	// use a static timestamp to make sure tests don't fail on slower systems or during a process pause
+	now := time.Now()
+	end := now.Add(time.Hour)
+	testCases := []struct {
+		testID  string
+		bounds  Bounds
+		atStart bool
+		atEnd   bool
+	}{
+		{
+			testID:  "Closed-open",
+			bounds:  ClosedOpen,
+			atStart: true,
+			atEnd:   false,
+		},
+		{
+			testID:  "Closed",
+			bounds:  Closed,
+			atStart: true,
+			atEnd:   true,
+		},
+		{
+			testID:  "Open",
+			bounds:  Open,
+			atStart: false,
+			atEnd:   false,
+		},
+		{
+			testID:  "Open-closed",
+			bounds:  OpenClosed,
+			atStart: false,
+			atEnd:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			perPeriod := []Period{TimeWindow{StartTime: now, EndTime: end, Identifier: "A", Bounds: tc.bounds}}
+			perCall := WithBounds(tc.bounds, TimeWindow{StartTime: now, EndTime: end, Identifier: "A"})
+			for _, periods := range [][]Period{perPeriod, perCall} {
+				for _, check := range []struct {
+					ts       time.Time
+					expected bool
+				}{
+					{now, tc.atStart},
+					{now.Add(time.Nanosecond), true},
+					{end.Add(-time.Nanosecond), true},
+					{end, tc.atEnd},
+					{end.Add(time.Nanosecond), false},
+				} {
+					_, err := MostSpecificPeriod(check.ts, periods...)
+					if (err == nil) != check.expected {
+						t.Errorf("%s containing %v is %v, expected %v", tc.bounds, check.ts, err == nil, check.expected)
+					}
+					if got := len(ValidTimePeriods(check.ts, periods...)) == 1; got != check.expected {
+						t.Errorf("%s valid at %v is %v, expected %v", tc.bounds, check.ts, got, check.expected)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGetChangeOversBounds(t *testing.T) {
+	day := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+	next := day.AddDate(0, 0, 1)
+	last := next.AddDate(0, 0, 1)
+	endOfDay := -time.Millisecond
+	testCases := []struct {
+		testID   string
+		periods  []Period
+		result   []time.Time
+		timeline []string
+	}{
+		{
+			testID: "Closed days ending at the last millisecond",
+			periods: []Period{
+				TimeWindow{StartTime: day, EndTime: next.Add(endOfDay), Identifier: "A", Bounds: Closed},
+				TimeWindow{StartTime: next, EndTime: last.Add(endOfDay), Identifier: "B", Bounds: Closed},
+			},
+			result: []time.Time{day, next.Add(endOfDay + time.Nanosecond), next, last.Add(endOfDay + time.Nanosecond)},
+			timeline: []string{
+				"A\t" + day.String() + "\t" + next.Add(endOfDay+time.Nanosecond).String(),
+				"B\t" + next.String() + "\t" + last.Add(endOfDay+time.Nanosecond).String(),
+			},
+		},
+		{
+			testID: "Open-closed days",
+			periods: []Period{
+				TimeWindow{StartTime: day, EndTime: next, Identifier: "A", Bounds: OpenClosed},
+				TimeWindow{StartTime: next, EndTime: last, Identifier: "B", Bounds: OpenClosed},
+			},
+			result: []time.Time{day.Add(time.Nanosecond), next.Add(time.Nanosecond), last.Add(time.Nanosecond)},
+			timeline: []string{
+				"A\t" + day.Add(time.Nanosecond).String() + "\t" + next.Add(time.Nanosecond).String(),
+				"B\t" + next.Add(time.Nanosecond).String() + "\t" + last.Add(time.Nanosecond).String(),
+			},
+		},
+		{
+			testID: "Closed day overlapping the next one at midnight",
+			periods: []Period{
+				TimeWindow{StartTime: day, EndTime: next, Identifier: "A", Bounds: Closed},
+				TimeWindow{StartTime: next, EndTime: last, Identifier: "B"},
+			},
+			// at midnight both contain the instant and the shorter B wins
+			result: []time.Time{day, next, last},
+			timeline: []string{
+				"A\t" + day.String() + "\t" + next.String(),
+				"B\t" + next.String() + "\t" + last.String(),
+			},
+		},
+		{
+			testID: "Open instant is empty",
+			periods: []Period{
+				TimeWindow{StartTime: day, EndTime: day.Add(time.Nanosecond), Identifier: "A", Bounds: Open},
+			},
+			result: []time.Time{},
+		},
+		{
+			testID: "Closed instant",
+			periods: []Period{
+				TimeWindow{StartTime: day, EndTime: day, Identifier: "A", Bounds: Closed},
+			},
+			result:   []time.Time{day, day.Add(time.Nanosecond)},
+			timeline: []string{"A\t" + day.String() + "\t" + day.Add(time.Nanosecond).String()},
+		},
+		{
+			testID: "Closed recurring occurrences",
+			periods: []Period{
+				RecurringPeriod{
+					StartTime:  day,
+					Duration:   time.Hour,
+					Identifier: "A",
+					Bounds:     Closed,
+					Recurrence: Recurrence{Frequency: Daily, Count: 2},
+				},
+			},
+			result: []time.Time{day, day.Add(time.Hour + time.Nanosecond), next, next.Add(time.Hour + time.Nanosecond)},
+			timeline: []string{
+				"A\t" + day.String() + "\t" + day.Add(time.Hour+time.Nanosecond).String(),
+				"A\t" + next.String() + "\t" + next.Add(time.Hour+time.Nanosecond).String(),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			changeovers := GetChangeOvers(tc.periods...)
+			if !slicesEqual(changeovers, tc.result) {
+				t.Errorf("Expected changeovers %v but got %v", tc.result, changeovers)
+			}
+			var timeline []string
+			for _, p := range GenerateTimeline(tc.periods...) {
+				timeline = append(timeline, p.(TimeWindow).String())
+			}
+			if !slicesEqual(timeline, tc.timeline) {
+				t.Errorf("Expected timeline %q but got %q", tc.timeline, timeline)
+			}
+		})
+	}
+}
@@ -0,0 +1,452 @@
+package msp
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Granularity is the calendar unit of a period. Under ShortestDuration,
+// periods with a Granularity are as specific as their unit's nominal length
+// rather than their elapsed time, so a local day shortened or lengthened by
+// a daylight saving transition still ranks like any other day.
+type Granularity int
+
+const (
+	// NoGranularity ranks a period by its elapsed time. It is the default
+	// for periods that do not implement Granular.
+	NoGranularity Granularity = iota
+	CalendarDay
+	// CalendarWeek is a week, by default an ISO 8601 week starting on
+	// Monday.
+	CalendarWeek
+	CalendarMonth
+	CalendarQuarter
+	CalendarYear
+)
+
+// Nominal lengths of the calendar units, based on the average Gregorian
+// year of 365.2425 days.
+const (
+	nominalDay   = 24 * time.Hour
+	nominalYear  = 365*nominalDay + 5*time.Hour + 49*time.Minute + 12*time.Second
+	nominalMonth = nominalYear / 12
+)
+
+// String returns the name of the unit.
+func (g Granularity) String() string {
+	switch g {
+	case NoGranularity:
+		return "none"
+	case CalendarDay:
+		return "day"
+	case CalendarWeek:
+		return "week"
+	case CalendarMonth:
+		return "month"
+	case CalendarQuarter:
+		return "quarter"
+	case CalendarYear:
+		return "year"
+	}
+	return fmt.Sprintf("Granularity(%d)", int(g))
+}
+
+// Nominal returns the length used to rank periods of the unit: 24 hours for
+// a day, 7 days for a week and a twelfth, a quarter or all of the average
+// Gregorian year for months, quarters and years. NoGranularity returns 0.
+func (g Granularity) Nominal() time.Duration {
+	switch g {
+	case CalendarDay:
+		return nominalDay
+	case CalendarWeek:
+		return 7 * nominalDay
+	case CalendarMonth:
+		return nominalMonth
+	case CalendarQuarter:
+		return 3 * nominalMonth
+	case CalendarYear:
+		return nominalYear
+	}
+	return 0
+}
+
+// Granular is implemented by periods that span a calendar unit.
+type Granular interface {
+	GetGranularity() Granularity
+}
+
+// GetGranularity returns p's granularity if it implements Granular, or
+// NoGranularity.
+func GetGranularity(p Period) Granularity {
+	if x, ok := p.(Granular); ok {
+		return x.GetGranularity()
+	}
+	return NoGranularity
+}
+
+// Compile-time interface checks.
+var (
+	_ Period      = CalendarPeriod{}
+	_ Prioritized = CalendarPeriod{}
+	_ Granular    = CalendarPeriod{}
+)
+
+// CalendarPeriod is the local day, week, month, quarter or year containing
+// Date. It starts at the first instant of its first day and ends
+// at the first instant of the following unit in Location, so consecutive
+// calendar periods neither overlap nor leave gaps, also across daylight
+// saving transitions. Where midnight does not exist because clocks skip
+// ahead, the day starts at the first instant after the gap; where midnight
+// occurs twice because clocks fall back, the day starts at the earlier one.
+type CalendarPeriod struct {
+	Granularity Granularity
+	// Date is any time within the period. Its date in Location selects the
+	// day, week, month, quarter or year.
+	Date time.Time
+	// Location defines the calendar. A nil Location is Date's location.
+	Location *time.Location
+	// Identifier names the period. If empty, GetIdentifier returns a label
+	// such as 2024-06-15, 2024-W24, 2024-06, 2024-Q2 or 2024.
+	Identifier string
+	Priority   int
+	// WeekStart is the first day of a CalendarWeek. The zero value selects
+	// ISO 8601 weeks starting on Monday.
+	WeekStart WeekStart
+	// FiscalYearStart is the month in which a CalendarQuarter or
+	// CalendarYear starts. Zero means January.
+	FiscalYearStart time.Month
+}
+
+// GetIdentifier returns the period's identifier, or its label if none is
+// set.
+func (p CalendarPeriod) GetIdentifier() string {
+	if p.Identifier != "" {
+		return p.Identifier
+	}
+	return p.Label()
+}
+
+// GetStartTime returns the first instant of the period.
+func (p CalendarPeriod) GetStartTime() time.Time {
+	y, m, d := p.firstDay()
+	return StartOfDay(y, m, d, p.location())
+}
+
+// GetEndTime returns the first instant after the period.
+func (p CalendarPeriod) GetEndTime() time.Time {
+	y, m, d := p.unit().next(p.firstDay())
+	return StartOfDay(y, m, d, p.location())
+}
+
+// GetPriority returns the period's explicit priority.
+func (p CalendarPeriod) GetPriority() int {
+	return p.Priority
+}
+
+// GetGranularity returns the period's calendar unit. The zero Granularity
+// is treated as CalendarDay.
+func (p CalendarPeriod) GetGranularity() Granularity {
+	if p.Granularity == NoGranularity {
+		return CalendarDay
+	}
+	return p.Granularity
+}
+
+// Label returns the conventional name of the period: 2024-06-15 for a day,
+// 2024-W24 for an ISO week, 2024-06 for a month, 2024-Q2 for a quarter and
+// 2024 for a year. Weeks starting on another day than Monday are named by
+// their first day and length in ISO 8601 interval notation, as in
+// 2024-06-09/P1W. Quarters and years of fiscal years starting in another
+// month than January are named after the year in which the fiscal year
+// ends, as in FY2025-Q1 and FY2025.
+func (p CalendarPeriod) Label() string {
+	return p.unit().label(p.firstDay())
+}
+
+// String returns the label followed by the bounds of the period.
+func (p CalendarPeriod) String() string {
+	return fmt.Sprintf("%s [%s, %s)", p.GetIdentifier(), p.GetStartTime().Format(time.RFC3339), p.GetEndTime().Format(time.RFC3339))
+}
+
+// location returns the location of the calendar.
+func (p CalendarPeriod) location() *time.Location {
+	if p.Location != nil {
+		return p.Location
+	}
+	return p.Date.Location()
+}
+
+// firstDay returns the date of the first day of the period.
+func (p CalendarPeriod) firstDay() (year int, month time.Month, day int) {
+	return p.unit().first(p.Date.In(p.location()).Date())
+}
+
+// unit returns the calendar unit of the period.
+func (p CalendarPeriod) unit() calendarUnit {
+	c := Calendar{WeekStart: p.WeekStart, FiscalYearStart: p.FiscalYearStart}
+	return c.unit(p.GetGranularity())
+}
+
+// normalizeDate returns the date of the given, possibly overflowing, day.
+func normalizeDate(year int, month time.Month, day int) (int, time.Month, int) {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC).Date()
+}
+
+// StartOfDay returns the first instant of the given date in loc. Day and
+// month may overflow as for time.Date. Unlike time.Date, which leaves the
+// outcome for non-existent and repeated wall-clock times unspecified, it
+// returns the end of the gap when midnight is skipped by a daylight saving
+// transition and the earlier instant when midnight occurs twice.
+func StartOfDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	year, month, day = normalizeDate(year, month, day)
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	started := func(t time.Time) bool {
+		y, m, d := t.In(loc).Date()
+		return !time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Before(date)
+	}
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if started(t) && !started(t.Add(-time.Nanosecond)) {
+		return t
+	}
+	// daylight saving transitions move clocks by less than a day, so the
+	// first instant of the day lies within a day of the guess
+	from := t.Add(-nominalDay)
+	offset := sort.Search(int(2*nominalDay), func(i int) bool {
+		return started(from.Add(time.Duration(i)))
+	})
+	return from.Add(time.Duration(offset))
+}
+
+// WeekStart is the first day of a week, counted in days after Monday so
+// that the zero value selects ISO 8601 weeks.
+type WeekStart int
+
+// WeeksStartingOn returns the WeekStart of weeks starting on day.
+func WeeksStartingOn(day time.Weekday) WeekStart {
+	return WeekStart((int(day) + 6) % 7)
+}
+
+// Weekday returns the first day of the week.
+func (s WeekStart) Weekday() time.Weekday {
+	return time.Weekday((int(s)%7 + 8) % 7)
+}
+
+// Calendar generates the consecutive days, weeks, months, quarters and years
+// covering a range as CalendarPeriod values, ready to be resolved by
+// MostSpecificPeriod. The periods keep their granularity, so a day shortened
+// by a daylight saving transition still ranks as a day, and they tile the
+// range without gaps or overlaps.
+type Calendar struct {
+	// Location defines the calendar. A nil Location is the location of the
+	// start of the range.
+	Location *time.Location
+	// WeekStart is the first day of the weeks generated by Weeks. The zero
+	// value selects ISO 8601 weeks starting on Monday.
+	WeekStart WeekStart
+	// FiscalYearStart is the month in which the years and quarters
+	// generated by Years and Quarters start. Zero means January.
+	FiscalYearStart time.Month
+}
+
+// Days returns the local days overlapping [from, to) in loc, named like
+// 2024-06-15.
+func Days(from, to time.Time, loc *time.Location) []CalendarPeriod {
+	return Calendar{Location: loc}.Days(from, to)
+}
+
+// ISOWeeks returns the ISO 8601 weeks overlapping [from, to) in loc, named
+// like 2024-W24.
+func ISOWeeks(from, to time.Time, loc *time.Location) []CalendarPeriod {
+	return Calendar{Location: loc}.Weeks(from, to)
+}
+
+// Months returns the months overlapping [from, to) in loc, named like
+// 2024-06.
+func Months(from, to time.Time, loc *time.Location) []CalendarPeriod {
+	return Calendar{Location: loc}.Months(from, to)
+}
+
+// Quarters returns the calendar quarters overlapping [from, to) in loc,
+// named like 2024-Q2.
+func Quarters(from, to time.Time, loc *time.Location) []CalendarPeriod {
+	return Calendar{Location: loc}.Quarters(from, to)
+}
+
+// Years returns the calendar years overlapping [from, to) in loc, named
+// like 2024.
+func Years(from, to time.Time, loc *time.Location) []CalendarPeriod {
+	return Calendar{Location: loc}.Years(from, to)
+}
+
+// Days returns the days overlapping [from, to).
+func (c Calendar) Days(from, to time.Time) []CalendarPeriod {
+	return c.periods(from, to, CalendarDay)
+}
+
+// Weeks returns the weeks starting on c.WeekStart that overlap [from, to).
+func (c Calendar) Weeks(from, to time.Time) []CalendarPeriod {
+	return c.periods(from, to, CalendarWeek)
+}
+
+// Months returns the months overlapping [from, to).
+func (c Calendar) Months(from, to time.Time) []CalendarPeriod {
+	return c.periods(from, to, CalendarMonth)
+}
+
+// Quarters returns the quarters of the fiscal years overlapping [from, to).
+func (c Calendar) Quarters(from, to time.Time) []CalendarPeriod {
+	return c.periods(from, to, CalendarQuarter)
+}
+
+// Years returns the fiscal years overlapping [from, to).
+func (c Calendar) Years(from, to time.Time) []CalendarPeriod {
+	return c.periods(from, to, CalendarYear)
+}
+
+// Hierarchy returns the years, quarters, months, weeks and days overlapping
+// [from, to), so that MostSpecificPeriod resolves any time in the range to
+// its day and RankedPeriods lists the enclosing units from day to year.
+func (c Calendar) Hierarchy(from, to time.Time) []Period {
+	var out []Period
+	for _, g := range []Granularity{CalendarYear, CalendarQuarter, CalendarMonth, CalendarWeek, CalendarDay} {
+		for _, p := range c.periods(from, to, g) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// periods returns the periods of granularity g overlapping [from, to).
+func (c Calendar) periods(from, to time.Time, g Granularity) []CalendarPeriod {
+	if !from.Before(to) {
+		return nil
+	}
+	loc := c.Location
+	if loc == nil {
+		loc = from.Location()
+	}
+	p := CalendarPeriod{
+		Granularity:     g,
+		Date:            from,
+		Location:        loc,
+		WeekStart:       c.WeekStart,
+		FiscalYearStart: c.FiscalYearStart,
+	}
+	var out []CalendarPeriod
+	for p.Date = p.GetStartTime(); p.Date.Before(to); p.Date = p.GetEndTime() {
+		out = append(out, p)
+	}
+	return out
+}
+
+// calendarUnit describes a unit of a Calendar by dates.
+type calendarUnit struct {
+	// first returns the first day of the unit containing a date.
+	first func(year int, month time.Month, day int) (int, time.Month, int)
+	// next returns the first day of the unit following the one starting on
+	// a date.
+	next func(year int, month time.Month, day int) (int, time.Month, int)
+	// label names the unit starting on a date.
+	label func(year int, month time.Month, day int) string
+}
+
+// unit returns the unit of granularity g. NoGranularity is a day.
+func (c Calendar) unit(g Granularity) calendarUnit {
+	switch g {
+	case CalendarWeek:
+		label := isoWeekLabel
+		if c.WeekStart.Weekday() != time.Monday {
+			label = weekLabel
+		}
+		return calendarUnit{
+			first: func(y int, m time.Month, d int) (int, time.Month, int) {
+				weekday := time.Date(y, m, d, 12, 0, 0, 0, time.UTC).Weekday()
+				return normalizeDate(y, m, d-(int(weekday)-int(c.WeekStart.Weekday())+7)%7)
+			},
+			next:  func(y int, m time.Month, d int) (int, time.Month, int) { return normalizeDate(y, m, d+7) },
+			label: label,
+		}
+	case CalendarMonth:
+		return calendarUnit{
+			first: func(y int, m time.Month, _ int) (int, time.Month, int) { return y, m, 1 },
+			next:  func(y int, m time.Month, _ int) (int, time.Month, int) { return normalizeDate(y, m+1, 1) },
+			label: monthLabel,
+		}
+	case CalendarQuarter:
+		return calendarUnit{
+			first: func(y int, m time.Month, _ int) (int, time.Month, int) {
+				return normalizeDate(y, m-time.Month(c.fiscalMonth(m)%3), 1)
+			},
+			next:  func(y int, m time.Month, _ int) (int, time.Month, int) { return normalizeDate(y, m+3, 1) },
+			label: c.quarterLabel,
+		}
+	case CalendarYear:
+		return calendarUnit{
+			first: func(y int, m time.Month, _ int) (int, time.Month, int) {
+				return normalizeDate(y, m-time.Month(c.fiscalMonth(m)), 1)
+			},
+			next:  func(y int, m time.Month, _ int) (int, time.Month, int) { return normalizeDate(y+1, m, 1) },
+			label: c.yearLabel,
+		}
+	}
+	return calendarUnit{
+		first: func(y int, m time.Month, d int) (int, time.Month, int) { return y, m, d },
+		next:  func(y int, m time.Month, d int) (int, time.Month, int) { return normalizeDate(y, m, d+1) },
+		label: dayLabel,
+	}
+}
+
+// fiscalMonth returns the number of months between the start of the fiscal
+// year and month.
+func (c Calendar) fiscalMonth(month time.Month) int {
+	start := c.FiscalYearStart
+	if start == 0 {
+		start = time.January
+	}
+	return (int(month-start) + 12) % 12
+}
+
+// fiscalYear returns the name of the year containing the month starting on
+// the given date.
+func (c Calendar) fiscalYear(year int, month time.Month) string {
+	if c.FiscalYearStart <= time.January {
+		return fmt.Sprintf("%04d", year)
+	}
+	if month >= c.FiscalYearStart {
+		year++
+	}
+	return fmt.Sprintf("FY%04d", year)
+}
+
+// yearLabel names the year starting on a date.
+func (c Calendar) yearLabel(year int, month time.Month, _ int) string {
+	return c.fiscalYear(year, month)
+}
+
+// quarterLabel names the quarter starting on a date.
+func (c Calendar) quarterLabel(year int, month time.Month, _ int) string {
+	return fmt.Sprintf("%s-Q%d", c.fiscalYear(year, month), c.fiscalMonth(month)/3+1)
+}
+
+// dayLabel names a day.
+func dayLabel(year int, month time.Month, day int) string {
+	return fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+}
+
+// isoWeekLabel names the ISO week containing a date.
+func isoWeekLabel(year int, month time.Month, day int) string {
+	year, week := time.Date(year, month, day, 12, 0, 0, 0, time.UTC).ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// weekLabel names the week starting on a date in ISO 8601 interval
+// notation, which cannot be mistaken for an ISO week.
+func weekLabel(year int, month time.Month, day int) string {
+	return dayLabel(year, month, day) + "/P1W"
+}
+
+// monthLabel names the month containing a date.
+func monthLabel(year int, month time.Month, _ int) string {
+	return fmt.Sprintf("%04d-%02d", year, month)
+}
@@ -0,0 +1,408 @@
+package msp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// loadLocation returns the named location or skips the test.
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	return loc
+}
+
+func TestCalendarPeriod(t *testing.T) {
+	berlin := loadLocation(t, "Europe/Berlin")
+	utc := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2024, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	testCases := []struct {
+		testID string
+		period CalendarPeriod
+		id     string
+		start  time.Time
+		end    time.Time
+	}{
+		{
+			testID: "Day",
+			period: CalendarPeriod{Granularity: CalendarDay, Date: time.Date(2024, time.June, 15, 13, 0, 0, 0, berlin)},
+			id:     "2024-06-15",
+			start:  utc(time.June, 14, 22),
+			end:    utc(time.June, 15, 22),
+		},
+		{
+			testID: "Zero granularity is a day",
+			period: CalendarPeriod{Date: time.Date(2024, time.June, 15, 13, 0, 0, 0, berlin)},
+			id:     "2024-06-15",
+			start:  utc(time.June, 14, 22),
+			end:    utc(time.June, 15, 22),
+		},
+		{
+			testID: "Date read in Location",
+			period: CalendarPeriod{Granularity: CalendarDay, Date: utc(time.June, 14, 23), Location: berlin},
+			id:     "2024-06-15",
+			start:  utc(time.June, 14, 22),
+			end:    utc(time.June, 15, 22),
+		},
+		{
+			testID: "Day of the spring DST transition lasts 23h",
+			period: CalendarPeriod{Granularity: CalendarDay, Date: time.Date(2024, time.March, 31, 12, 0, 0, 0, berlin)},
+			id:     "2024-03-31",
+			start:  utc(time.March, 30, 23),
+			end:    utc(time.March, 31, 22),
+		},
+		{
+			testID: "ISO week across a month",
+			period: CalendarPeriod{Granularity: CalendarWeek, Date: time.Date(2024, time.July, 2, 12, 0, 0, 0, berlin)},
+			id:     "2024-W27",
+			start:  utc(time.June, 30, 22),
+			end:    utc(time.July, 7, 22),
+		},
+		{
+			testID: "ISO week belonging to the previous year",
+			period: CalendarPeriod{Granularity: CalendarWeek, Date: time.Date(2021, time.January, 3, 12, 0, 0, 0, time.UTC)},
+			id:     "2020-W53",
+			start:  time.Date(2020, time.December, 28, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2021, time.January, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			testID: "Week starting on Sunday",
+			period: CalendarPeriod{Granularity: CalendarWeek, Date: time.Date(2024, time.July, 2, 12, 0, 0, 0, berlin), WeekStart: WeeksStartingOn(time.Sunday)},
+			id:     "2024-06-30/P1W",
+			start:  utc(time.June, 29, 22),
+			end:    utc(time.July, 6, 22),
+		},
+		{
+			testID: "Month",
+			period: CalendarPeriod{Granularity: CalendarMonth, Date: time.Date(2024, time.March, 15, 12, 0, 0, 0, berlin)},
+			id:     "2024-03",
+			start:  utc(time.February, 29, 23),
+			end:    utc(time.March, 31, 22),
+		},
+		{
+			testID: "Quarter",
+			period: CalendarPeriod{Granularity: CalendarQuarter, Date: time.Date(2024, time.June, 15, 12, 0, 0, 0, berlin), Identifier: "summer rates"},
+			id:     "summer rates",
+			start:  utc(time.March, 31, 22),
+			end:    utc(time.June, 30, 22),
+		},
+		{
+			testID: "Fiscal quarter",
+			period: CalendarPeriod{Granularity: CalendarQuarter, Date: time.Date(2024, time.November, 15, 12, 0, 0, 0, berlin), FiscalYearStart: time.October},
+			id:     "FY2025-Q1",
+			start:  utc(time.September, 30, 22),
+			end:    time.Date(2024, time.December, 31, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			testID: "Year",
+			period: CalendarPeriod{Granularity: CalendarYear, Date: time.Date(2024, time.June, 15, 12, 0, 0, 0, berlin)},
+			id:     "2024",
+			start:  time.Date(2023, time.December, 31, 23, 0, 0, 0, time.UTC),
+			end:    time.Date(2024, time.December, 31, 23, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			if id := tc.period.GetIdentifier(); id != tc.id {
+				t.Errorf("Identifier %q does not match expected %q", id, tc.id)
+			}
+			if start := tc.period.GetStartTime(); !start.Equal(tc.start) {
+				t.Errorf("Start %v does not match expected %v", start, tc.start)
+			}
+			if end := tc.period.GetEndTime(); !end.Equal(tc.end) {
+				t.Errorf("End %v does not match expected %v", end, tc.end)
+			}
+		})
+	}
+}
+
+func TestStartOfDay(t *testing.T) {
+	// Cuba moves its clocks at midnight, skipping it in March and repeating
+	// it in November.
+	havana := loadLocation(t, "America/Havana")
+	testCases := []struct {
+		testID string
+		month  time.Month
+		day    int
+		result time.Time
+	}{
+		{
+			testID: "Ordinary midnight",
+			month:  time.June,
+			day:    15,
+			result: time.Date(2024, time.June, 15, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			testID: "Skipped midnight starts after the gap",
+			month:  time.March,
+			day:    10,
+			result: time.Date(2024, time.March, 10, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			testID: "Day after the skipped midnight",
+			month:  time.March,
+			day:    11,
+			result: time.Date(2024, time.March, 11, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			testID: "Repeated midnight starts at the first one",
+			month:  time.November,
+			day:    3,
+			result: time.Date(2024, time.November, 3, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			testID: "Overflowing day",
+			month:  time.February,
+			day:    30,
+			result: time.Date(2024, time.March, 1, 5, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			if start := StartOfDay(2024, tc.month, tc.day, havana); !start.Equal(tc.result) {
+				t.Errorf("Start %v does not match expected %v", start, tc.result)
+			}
+		})
+	}
+}
+
+func TestMostSpecificPeriodCalendar(t *testing.T) {
+	berlin := loadLocation(t, "Europe/Berlin")
+	newYork := loadLocation(t, "America/New_York")
+	// Sunday, March 31st 2024 lasts 23 hours in Berlin.
+	dstDay := CalendarPeriod{Granularity: CalendarDay, Date: time.Date(2024, time.March, 31, 12, 0, 0, 0, berlin), Identifier: "berlin"}
+	day := CalendarPeriod{Granularity: CalendarDay, Date: time.Date(2024, time.March, 31, 12, 0, 0, 0, newYork), Identifier: "new york"}
+	week := CalendarPeriod{Granularity: CalendarWeek, Date: time.Date(2024, time.March, 31, 12, 0, 0, 0, berlin), Identifier: "week"}
+	month := CalendarPeriod{Granularity: CalendarMonth, Date: time.Date(2024, time.March, 31, 12, 0, 0, 0, berlin), Identifier: "month"}
+	ts := time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		testID  string
+		periods []Period
+		result  string
+	}{
+		{
+			testID:  "Days in different zones tie on granularity",
+			periods: []Period{dstDay, day},
+			result:  "new york",
+		},
+		{
+			testID:  "Day beats week",
+			periods: []Period{week, day, month},
+			result:  "new york",
+		},
+		{
+			testID:  "Week beats month",
+			periods: []Period{month, week},
+			result:  "week",
+		},
+		{
+			testID:  "Shorter window beats a calendar day",
+			periods: []Period{dstDay, TimeWindow{StartTime: ts.Add(-time.Hour), EndTime: ts.Add(time.Hour), Identifier: "window"}},
+			result:  "window",
+		},
+		{
+			testID:  "Granularity survives WithBounds",
+			periods: WithBounds(Closed, dstDay, day),
+			result:  "new york",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			id, err := MostSpecificPeriod(ts, tc.periods...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if id != tc.result {
+				t.Errorf("Result %q does not match expected %q", id, tc.result)
+			}
+		})
+	}
+}
+
+// identifiers returns the identifiers of periods.
+func identifiers(periods []CalendarPeriod) []string {
+	var out []string
+	for _, p := range periods {
+		out = append(out, p.GetIdentifier())
+	}
+	return out
+}
+
+func TestCalendarGenerators(t *testing.T) {
+	berlin := loadLocation(t, "Europe/Berlin")
+	newYork := loadLocation(t, "America/New_York")
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 12, 0, 0, 0, berlin)
+	}
+	testCases := []struct {
+		testID      string
+		result      []CalendarPeriod
+		ids         []string
+		start       time.Time
+		end         time.Time
+		granularity Granularity
+	}{
+		{
+			testID:      "Days across the DST change",
+			result:      Days(at(2024, time.March, 30), at(2024, time.April, 1), berlin),
+			ids:         []string{"2024-03-30", "2024-03-31", "2024-04-01"},
+			start:       time.Date(2024, time.March, 30, 0, 0, 0, 0, berlin),
+			end:         time.Date(2024, time.April, 2, 0, 0, 0, 0, berlin),
+			granularity: CalendarDay,
+		},
+		{
+			testID:      "Short day in New York",
+			result:      Days(at(2024, time.March, 10), at(2024, time.March, 10).Add(time.Hour), newYork),
+			ids:         []string{"2024-03-10"},
+			start:       time.Date(2024, time.March, 10, 0, 0, 0, 0, newYork),
+			end:         time.Date(2024, time.March, 11, 0, 0, 0, 0, newYork),
+			granularity: CalendarDay,
+		},
+		{
+			testID:      "ISO weeks across the new year",
+			result:      ISOWeeks(at(2020, time.December, 25), at(2021, time.January, 5), berlin),
+			ids:         []string{"2020-W52", "2020-W53", "2021-W01"},
+			start:       time.Date(2020, time.December, 21, 0, 0, 0, 0, berlin),
+			end:         time.Date(2021, time.January, 11, 0, 0, 0, 0, berlin),
+			granularity: CalendarWeek,
+		},
+		{
+			testID:      "Weeks of the zero Calendar are ISO weeks",
+			result:      Calendar{Location: berlin}.Weeks(at(2024, time.June, 12), at(2024, time.June, 13)),
+			ids:         []string{"2024-W24"},
+			start:       time.Date(2024, time.June, 10, 0, 0, 0, 0, berlin),
+			end:         time.Date(2024, time.June, 17, 0, 0, 0, 0, berlin),
+			granularity: CalendarWeek,
+		},
+		{
+			testID:      "Weeks starting on Sunday",
+			result:      Calendar{Location: berlin, WeekStart: WeeksStartingOn(time.Sunday)}.Weeks(at(2024, time.December, 25), at(2025, time.January, 5)),
+			ids:         []string{"2024-12-22/P1W", "2024-12-29/P1W", "2025-01-05/P1W"},
+			start:       time.Date(2024, time.December, 22, 0, 0, 0, 0, berlin),
+			end:         time.Date(2025, time.January, 12, 0, 0, 0, 0, berlin),
+			granularity: CalendarWeek,
+		},
+		{
+			testID:      "Months",
+			result:      Months(at(2024, time.January, 31), at(2024, time.March, 1), berlin),
+			ids:         []string{"2024-01", "2024-02", "2024-03"},
+			start:       time.Date(2024, time.January, 1, 0, 0, 0, 0, berlin),
+			end:         time.Date(2024, time.April, 1, 0, 0, 0, 0, berlin),
+			granularity: CalendarMonth,
+		},
+		{
+			testID:      "Quarters",
+			result:      Quarters(at(2024, time.June, 15), at(2024, time.July, 1), berlin),
+			ids:         []string{"2024-Q2", "2024-Q3"},
+			start:       time.Date(2024, time.April, 1, 0, 0, 0, 0, berlin),
+			end:         time.Date(2024, time.October, 1, 0, 0, 0, 0, berlin),
+			granularity: CalendarQuarter,
+		},
+		{
+			testID:      "Fiscal quarters starting in October",
+			result:      Calendar{Location: berlin, FiscalYearStart: time.October}.Quarters(at(2024, time.September, 15), at(2025, time.January, 15)),
+			ids:         []string{"FY2024-Q4", "FY2025-Q1", "FY2025-Q2"},
+			start:       time.Date(2024, time.July, 1, 0, 0, 0, 0, berlin),
+			end:         time.Date(2025, time.April, 1, 0, 0, 0, 0, berlin),
+			granularity: CalendarQuarter,
+		},
+		{
+			testID:      "Years",
+			result:      Years(at(2023, time.June, 15), at(2024, time.June, 15), berlin),
+			ids:         []string{"2023", "2024"},
+			start:       time.Date(2023, time.January, 1, 0, 0, 0, 0, berlin),
+			end:         time.Date(2025, time.January, 1, 0, 0, 0, 0, berlin),
+			granularity: CalendarYear,
+		},
+		{
+			testID:      "Fiscal years starting in April",
+			result:      Calendar{Location: berlin, FiscalYearStart: time.April}.Years(at(2024, time.March, 15), at(2024, time.April, 15)),
+			ids:         []string{"FY2024", "FY2025"},
+			start:       time.Date(2023, time.April, 1, 0, 0, 0, 0, berlin),
+			end:         time.Date(2025, time.April, 1, 0, 0, 0, 0, berlin),
+			granularity: CalendarYear,
+		},
+		{
+			testID: "Empty range",
+			result: Days(at(2024, time.June, 15), at(2024, time.June, 15), berlin),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			if ids := identifiers(tc.result); !reflect.DeepEqual(ids, tc.ids) {
+				t.Fatalf("Identifiers %v do not match expected %v", ids, tc.ids)
+			}
+			if len(tc.result) == 0 {
+				return
+			}
+			if start := tc.result[0].GetStartTime(); !start.Equal(tc.start) {
+				t.Errorf("Start %v does not match expected %v", start, tc.start)
+			}
+			if end := tc.result[len(tc.result)-1].GetEndTime(); !end.Equal(tc.end) {
+				t.Errorf("End %v does not match expected %v", end, tc.end)
+			}
+			for i, p := range tc.result {
+				if g := GetGranularity(p); g != tc.granularity {
+					t.Errorf("Granularity %v of %v does not match expected %v", g, p, tc.granularity)
+				}
+				if i > 0 && !p.GetStartTime().Equal(tc.result[i-1].GetEndTime()) {
+					t.Errorf("Period %v does not start where %v ends", p, tc.result[i-1])
+				}
+			}
+		})
+	}
+}
+
+func TestCalendarHierarchy(t *testing.T) {
+	berlin := loadLocation(t, "Europe/Berlin")
+	cal := Calendar{Location: berlin}
+	periods := cal.Hierarchy(time.Date(2024, time.January, 1, 0, 0, 0, 0, berlin), time.Date(2025, time.January, 1, 0, 0, 0, 0, berlin))
+	testCases := []struct {
+		testID string
+		ts     time.Time
+		result []string
+	}{
+		{
+			testID: "Ordinary day",
+			ts:     time.Date(2024, time.June, 15, 12, 0, 0, 0, berlin),
+			result: []string{"2024-06-15", "2024-W24", "2024-06", "2024-Q2", "2024"},
+		},
+		{
+			testID: "Midnight belongs to the new day",
+			ts:     time.Date(2024, time.July, 1, 0, 0, 0, 0, berlin),
+			result: []string{"2024-07-01", "2024-W27", "2024-07", "2024-Q3", "2024"},
+		},
+		{
+			testID: "DST day",
+			ts:     time.Date(2024, time.March, 31, 12, 0, 0, 0, berlin),
+			result: []string{"2024-03-31", "2024-W13", "2024-03", "2024-Q1", "2024"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			var ids []string
+			for _, p := range RankedPeriods(tc.ts, periods...) {
+				ids = append(ids, p.GetIdentifier())
+			}
+			if !reflect.DeepEqual(ids, tc.result) {
+				t.Errorf("Result %v does not match expected %v", ids, tc.result)
+			}
+		})
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	if weekday := WeekStart(0).Weekday(); weekday != time.Monday {
+		t.Errorf("Zero WeekStart %v does not match expected Monday", weekday)
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if got := WeeksStartingOn(weekday).Weekday(); got != weekday {
+			t.Errorf("Weekday %v does not match expected %v", got, weekday)
+		}
+	}
+}
@@ -1,62 +1,126 @@
 package msp
 
-import (
-	"sort"
-	"time"
-)
+import "time"
 
 // GetChangeOvers returns the sorted list of timestamps where the most
-// specific period changes from one identifier to another.
+// specific period changes from one identifier to another. Recurring periods
+// without end are expanded up to the horizon described at NewResolver; use
+// GetChangeOversBetween to choose the range instead.
 func GetChangeOvers(periods ...Period) (changeovers []time.Time) {
-	timeStamps := []time.Time{}
-	for _, x := range periods {
-		timeStamps = append(timeStamps, x.GetEndTime())
-		timeStamps = append(timeStamps, x.GetStartTime())
-	}
-	if len(timeStamps) == 0 {
-		return
-	}
-	sort.Slice(timeStamps, func(i, j int) bool {
-		return timeStamps[i].Before(timeStamps[j])
-	})
-	// timeStamps is sorted, so this will always result in an unused time
-	// struct, as it's before the first
-	previousTs := timeStamps[0].Add(-10 * time.Nanosecond)
-	for _, ts := range timeStamps {
-		if ts.Equal(previousTs) {
-			continue
+	return DefaultPolicy.GetChangeOvers(periods...)
+}
+
+// GetChangeOvers returns the sorted list of timestamps where the period
+// ranked highest under the policy changes from one identifier to another.
+func (p Policy) GetChangeOvers(periods ...Period) (changeovers []time.Time) {
+	return p.NewResolver(periods...).changeOvers()
+}
+
+// changeOvers returns the boundaries at which the winning identifier changes.
+func (r *Resolver) changeOvers() (changeovers []time.Time) {
+	previous := r.head.identifier()
+	for i, ts := range r.bounds {
+		if !r.horizon.IsZero() && ts.After(r.horizon) {
+			break
 		}
-		previousTs = ts
-		before := ts.Add(-1 * time.Nanosecond)
-		after := ts.Add(1 * time.Nanosecond)
-		from, _ := MostSpecificPeriod(before, periods...)
-		to, _ := MostSpecificPeriod(after, periods...)
-		if from == to {
+		current := r.winners[i].identifier()
+		if current == previous {
 			continue
 		}
+		previous = current
 		changeovers = append(changeovers, ts)
 	}
 	return
 }
 
+// GetChangeOversBetween returns the sorted list of timestamps in [from, to)
+// where the most specific period changes from one identifier to another.
+// Recurring periods are expanded within the range, so series without end
+// need no further bounding.
+func GetChangeOversBetween(from, to time.Time, periods ...Period) (changeovers []time.Time) {
+	return DefaultPolicy.GetChangeOversBetween(from, to, periods...)
+}
+
+// GetChangeOversBetween returns the sorted list of timestamps in [from, to)
+// where the period ranked highest under the policy changes from one
+// identifier to another.
+func (p Policy) GetChangeOversBetween(from, to time.Time, periods ...Period) (changeovers []time.Time) {
+	if !from.Before(to) {
+		return nil
+	}
+	for _, c := range p.changeoversBetween(from.Add(-time.Nanosecond), to.Add(-time.Nanosecond), periods) {
+		changeovers = append(changeovers, c.At)
+	}
+	return changeovers
+}
+
 // GetNextChangeOver returns the first changeover timestamp strictly after t.
-// If no such changeover exists, ErrNoNextChangeover is returned.
+// If no such changeover exists, ErrNoNextChangeover is returned. Recurring
+// periods without end are searched for up to a century past t and the bounds
+// of the other periods.
 func GetNextChangeOver(t time.Time, periods ...Period) (ts time.Time, err error) {
-	changeOvers := GetChangeOvers(periods...)
-	for _, ts := range changeOvers {
-		if ts.After(t) {
-			return ts, nil
+	return DefaultPolicy.GetNextChangeOver(t, periods...)
+}
+
+// GetNextChangeOver returns the first changeover under the policy strictly
+// after t. If no such changeover exists, ErrNoNextChangeover is returned.
+func (p Policy) GetNextChangeOver(t time.Time, periods ...Period) (ts time.Time, err error) {
+	c, err := p.nextChangeover(t, periods)
+	return c.At, err
+}
+
+// nextChangeoverLimit is how far past t and the bounds of the other periods
+// nextChangeover searches series without end.
+const nextChangeoverLimit = 100 * 365 * 24 * time.Hour
+
+// nextChangeover returns the first changeover strictly after t. Series
+// without end are expanded one window at a time, doubling the window until a
+// changeover is found or nextChangeoverLimit is reached.
+func (p Policy) nextChangeover(t time.Time, periods []Period) (Changeover, error) {
+	horizon := horizonOf(periods)
+	if horizon.IsZero() {
+		r := p.NewResolver(periods...)
+		previous := r.lookup(t).identifier()
+		for _, ts := range r.changeOvers() {
+			if ts.After(t) {
+				return Changeover{At: ts, From: previous, To: r.lookup(ts).identifier()}, nil
+			}
 		}
+		return Changeover{}, ErrNoNextChangeover
 	}
-	return time.Time{}, ErrNoNextChangeover
+	limit := horizon
+	if t.After(limit) {
+		limit = t
+	}
+	limit = limit.Add(nextChangeoverLimit)
+	from := t
+	for step := watchHorizon; from.Before(limit); step *= 2 {
+		to := from.Add(step)
+		if to.After(limit) {
+			to = limit
+		}
+		if next := p.changeoversBetween(from, to, periods); len(next) > 0 {
+			return next[0], nil
+		}
+		from = to
+	}
+	return Changeover{}, ErrNoNextChangeover
 }
 
 // FlattenPeriods returns an ordered list of period identifiers representing
-// the most specific period at each changeover point.
+// the most specific period at each changeover point. Like GetChangeOvers, it
+// expands recurring periods without end up to the horizon described at
+// NewResolver.
 func FlattenPeriods(periods ...Period) (ids []string) {
-	changeovers := GetChangeOvers(periods...)
-	for _, c := range changeovers {
-		id, err := MostSpecificPeriod(c, periods...)
+	return DefaultPolicy.FlattenPeriods(periods...)
+}
+
+// FlattenPeriods returns an ordered list of the identifiers ranked highest
+// under the policy at each changeover point.
+func (p Policy) FlattenPeriods(periods ...Period) (ids []string) {
+	r := p.NewResolver(periods...)
+	for _, c := range r.changeOvers() {
+		id, err := r.Resolve(c)
 		if err != nil {
 			continue
 		}
@@ -478,3 +478,57 @@ func TestGetNextChangeOver(t *testing.T) {
 		})
 	}
 }
+
+func TestGetChangeOversPriority(t *testing.T) {
+	now := time.Now()
+	periods := []Period{
+		TimeWindow{
+			StartTime:  now.Add(-10 * time.Minute),
+			EndTime:    now.Add(10 * time.Minute),
+			Identifier: "promo",
+			Priority:   1,
+		},
+		TimeWindow{
+			StartTime:  now.Add(-time.Minute),
+			EndTime:    now.Add(time.Minute),
+			Identifier: "hour",
+		},
+	}
+	changeovers := GetChangeOvers(periods...)
+	expected := []time.Time{now.Add(-10 * time.Minute), now.Add(10 * time.Minute)}
+	if !slicesEqual(changeovers, expected) {
+		t.Errorf("Expected %v but got %v", expected, changeovers)
+	}
+	ids := FlattenPeriods(periods...)
+	if !slicesEqual(ids, []string{"promo"}) {
+		t.Errorf("Expected [promo] but got %v", ids)
+	}
+}
+
+func TestGetChangeOversOpenEnded(t *testing.T) {
+	now := time.Now()
+	periods := []Period{
+		TimeWindow{
+			EndTime:    now,
+			Identifier: "legacy",
+		},
+		TimeWindow{
+			StartTime:  now,
+			Identifier: "current",
+		},
+		TimeWindow{
+			StartTime:  now.Add(time.Hour),
+			EndTime:    now.Add(2 * time.Hour),
+			Identifier: "promo",
+		},
+	}
+	changeovers := GetChangeOvers(periods...)
+	expected := []time.Time{now, now.Add(time.Hour), now.Add(2 * time.Hour)}
+	if !slicesEqual(changeovers, expected) {
+		t.Errorf("Expected %v but got %v", expected, changeovers)
+	}
+	next, err := GetNextChangeOver(now.Add(-time.Hour), periods...)
+	if err != nil || !next.Equal(now) {
+		t.Errorf("Expected next changeover %v but got %v (%v)", now, next, err)
+	}
+}
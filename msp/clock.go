@@ -0,0 +1,141 @@
+package msp
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time and waits for time to pass. It lets
+// time-dependent code such as Watcher run against a controllable clock in
+// tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After waits for d to elapse and then sends the current time on the
+	// returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// Compile-time interface check.
+var _ Clock = RealClock{}
+
+// RealClock is a Clock backed by the time package.
+type RealClock struct{}
+
+// Now returns time.Now().
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// After returns time.After(d).
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Compile-time interface check.
+var _ Clock = (*FakeClock)(nil)
+
+// FakeClock is a Clock whose time only moves when Set or Advance is called,
+// so tests can step deterministically through changeovers. It is safe for
+// concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+// fakeWaiter is a pending call to FakeClock.After.
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.changed = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the clock's current time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the clock's time once it has been
+// advanced by at least d.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), c: ch})
+	c.changed.Broadcast()
+	return ch
+}
+
+// Advance moves the clock forward by d, firing any waiters that are due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set moves the clock to t, firing any waiters that are due. Setting the
+// clock backwards fires nothing.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(t) {
+			pending = append(pending, w)
+			continue
+		}
+		w.c <- t
+	}
+	c.waiters = pending
+	c.changed.Broadcast()
+}
+
+// BlockUntil blocks until at least n calls to After are waiting for the
+// clock to advance. Use it to make sure the code under test is asleep
+// before advancing the clock.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.changed.Wait()
+	}
+}
+
+// Current returns the identifier of the most specific period at the clock's
+// current time. If no period is active, ErrNoValidPeriods is returned.
+func Current(clock Clock, periods ...Period) (id string, err error) {
+	return DefaultPolicy.Current(clock, periods...)
+}
+
+// Current returns the identifier of the period ranked highest under the
+// policy at the clock's current time.
+func (p Policy) Current(clock Clock, periods ...Period) (id string, err error) {
+	return p.MostSpecificPeriod(clock.Now(), periods...)
+}
+
+// NextChangeover returns the first changeover after the clock's current
+// time. If there is none, ErrNoNextChangeover is returned. As with
+// GetNextChangeOver, recurring periods without end are searched for up to a
+// century ahead.
+func NextChangeover(clock Clock, periods ...Period) (Changeover, error) {
+	return DefaultPolicy.NextChangeover(clock, periods...)
+}
+
+// NextChangeover returns the first changeover under the policy after the
+// clock's current time.
+func (p Policy) NextChangeover(clock Clock, periods ...Period) (Changeover, error) {
+	return p.nextChangeover(clock.Now(), periods)
+}
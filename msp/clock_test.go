@@ -0,0 +1,154 @@
+package msp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 8, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(now)
+	short := clock.After(time.Minute)
+	long := clock.After(time.Hour)
+	clock.BlockUntil(2)
+
+	clock.Advance(time.Minute)
+	select {
+	case ts := <-short:
+		if !ts.Equal(now.Add(time.Minute)) {
+			t.Errorf("Fired at %s, expected %s", ts, now.Add(time.Minute))
+		}
+	default:
+		t.Errorf("Expected the one minute waiter to fire")
+	}
+	select {
+	case <-long:
+		t.Errorf("The one hour waiter fired early")
+	default:
+	}
+
+	clock.Set(now.Add(2 * time.Hour))
+	select {
+	case <-long:
+	default:
+		t.Errorf("Expected the one hour waiter to fire")
+	}
+	if !clock.Now().Equal(now.Add(2 * time.Hour)) {
+		t.Errorf("Now %s does not match expected %s", clock.Now(), now.Add(2*time.Hour))
+	}
+	select {
+	case <-clock.After(0):
+	default:
+		t.Errorf("Expected a waiter for zero duration to fire immediately")
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 8, 0, 0, 0, time.UTC)
+	periods := []Period{
+		TimeWindow{
+			StartTime:  now.Add(-time.Hour),
+			EndTime:    now.Add(time.Hour),
+			Identifier: "A",
+		},
+		TimeWindow{
+			StartTime:  now.Add(30 * time.Minute),
+			EndTime:    now.Add(45 * time.Minute),
+			Identifier: "B",
+		},
+	}
+	testCases := []struct {
+		testID string
+		ts     time.Time
+		result string
+		err    error
+	}{
+		{
+			testID: "Before all periods",
+			ts:     now.Add(-2 * time.Hour),
+			result: "",
+			err:    ErrNoValidPeriods,
+		},
+		{
+			testID: "Outer period",
+			ts:     now,
+			result: "A",
+			err:    nil,
+		},
+		{
+			testID: "Inner period",
+			ts:     now.Add(30 * time.Minute),
+			result: "B",
+			err:    nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			id, err := Current(NewFakeClock(tc.ts), periods...)
+			if id != tc.result {
+				t.Errorf("Result '%s' does not match expected '%s'", id, tc.result)
+			}
+			if err != tc.err {
+				t.Errorf("Error '%v' does not match expected '%v'", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestNextChangeover(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 8, 0, 0, 0, time.UTC)
+	periods := []Period{
+		TimeWindow{
+			StartTime:  now.Add(-time.Hour),
+			EndTime:    now.Add(time.Hour),
+			Identifier: "A",
+		},
+		TimeWindow{
+			StartTime:  now.Add(30 * time.Minute),
+			EndTime:    now.Add(45 * time.Minute),
+			Identifier: "B",
+		},
+	}
+	testCases := []struct {
+		testID string
+		ts     time.Time
+		result Changeover
+		err    error
+	}{
+		{
+			testID: "Before all periods",
+			ts:     now.Add(-2 * time.Hour),
+			result: Changeover{At: now.Add(-time.Hour), From: "", To: "A"},
+			err:    nil,
+		},
+		{
+			testID: "Into the inner period",
+			ts:     now,
+			result: Changeover{At: now.Add(30 * time.Minute), From: "A", To: "B"},
+			err:    nil,
+		},
+		{
+			testID: "Exactly at a changeover",
+			ts:     now.Add(30 * time.Minute),
+			result: Changeover{At: now.Add(45 * time.Minute), From: "B", To: "A"},
+			err:    nil,
+		},
+		{
+			testID: "After all periods",
+			ts:     now.Add(time.Hour),
+			result: Changeover{},
+			err:    ErrNoNextChangeover,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			c, err := NextChangeover(NewFakeClock(tc.ts), periods...)
+			if c != tc.result {
+				t.Errorf("Result %+v does not match expected %+v", c, tc.result)
+			}
+			if err != tc.err {
+				t.Errorf("Error '%v' does not match expected '%v'", err, tc.err)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package msp
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
@@ -11,4 +12,31 @@ var (
 	ErrNoValidPeriods = errors.New("error: no valid periods available")
 	// ErrNoNextChangeover occurs when GetNextChangeover is called but there are no changeovers after t
 	ErrNoNextChangeover = errors.New("error: no valid changeovers available")
+	// ErrZeroLength occurs when a period contains no timestamp because its start time equals its end time
+	ErrZeroLength = errors.New("error: start time equals end time")
+	// ErrEmptyIdentifier occurs when a period has no identifier
+	ErrEmptyIdentifier = errors.New("error: period has no identifier")
+	// ErrMismatchedLocations occurs when a period's start and end times are in different locations
+	ErrMismatchedLocations = errors.New("error: start and end time are in different locations")
 )
+
+// ValidationError reports a problem with one of the periods passed to
+// Validate. Err is one of the sentinel errors above, so errors.Is works on a
+// ValidationError as well as on the joined error returned by Validate.
+type ValidationError struct {
+	// Index is the position of the offending period among the validated
+	// periods.
+	Index      int
+	Identifier string
+	Err        error
+}
+
+// Error describes the problem and the period it was found in.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v (period %d %q)", e.Err, e.Index, e.Identifier)
+}
+
+// Unwrap returns the sentinel error describing the problem.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,204 @@
+package msp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExclusionReason describes why a period cannot contain a timestamp.
+type ExclusionReason int
+
+const (
+	// NotStarted means the period starts after the timestamp.
+	NotStarted ExclusionReason = iota + 1
+	// Ended means the period ends at or before the timestamp.
+	Ended
+	// InvertedBounds means the period starts after it ends.
+	InvertedBounds
+	// NoOccurrence means no occurrence of a Recurring period contains the
+	// timestamp.
+	NoOccurrence
+)
+
+// String returns a short description of the reason.
+func (r ExclusionReason) String() string {
+	switch r {
+	case NotStarted:
+		return "not started"
+	case Ended:
+		return "ended"
+	case InvertedBounds:
+		return "start time is after end time"
+	case NoOccurrence:
+		return "no occurrence"
+	}
+	return fmt.Sprintf("ExclusionReason(%d)", int(r))
+}
+
+// DecidedByInputOrder is reported as Explanation.DecidedBy when no rule of
+// the policy could tell the winner and the runner-up apart.
+const DecidedByInputOrder = "input order"
+
+// Exclusion is an input period that does not contain the explained
+// timestamp.
+type Exclusion struct {
+	Period Period
+	// Index is the position of Period among the explained periods.
+	Index  int
+	Reason ExclusionReason
+}
+
+// Candidate is a period containing the explained timestamp.
+type Candidate struct {
+	// Period is the candidate itself, or the occurrence containing the
+	// timestamp for a Recurring period.
+	Period Period
+	// Index is the position of the input period among the explained
+	// periods.
+	Index     int
+	StartTime time.Time
+	// Duration is the length by which ShortestDuration ranks the period:
+	// the nominal length of its Granularity if it has one, and its elapsed
+	// time otherwise.
+	Duration    time.Duration
+	Granularity Granularity
+	Priority    int
+}
+
+// Explanation describes how MostSpecificPeriod arrives at its answer for a
+// timestamp.
+type Explanation struct {
+	Timestamp time.Time
+	// Excluded lists the input periods that do not contain Timestamp, in
+	// input order.
+	Excluded []Exclusion
+	// Candidates lists the periods containing Timestamp, most specific
+	// first. The first candidate, if any, is the winner.
+	Candidates []Candidate
+	// DecidedBy names the rule that ranked the winner above the runner-up,
+	// or DecidedByInputOrder if none did. It is empty when there are fewer
+	// than two candidates.
+	DecidedBy string
+}
+
+// Winner returns the winning period and true, or nil and false if no period
+// contains the timestamp.
+func (e Explanation) Winner() (Period, bool) {
+	if len(e.Candidates) == 0 {
+		return nil, false
+	}
+	return e.Candidates[0].Period, true
+}
+
+// String renders the explanation as a human-readable report.
+func (e Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Timestamp: %s\n", e.Timestamp)
+	if w, ok := e.Winner(); ok {
+		fmt.Fprintf(&b, "Winner: %s\n", w.GetIdentifier())
+	} else {
+		fmt.Fprintf(&b, "Winner: none\n")
+	}
+	if e.DecidedBy != "" {
+		fmt.Fprintf(&b, "Decided by: %s\n", e.DecidedBy)
+	}
+	if len(e.Candidates) > 0 {
+		fmt.Fprintf(&b, "Candidates:\n")
+		for i, c := range e.Candidates {
+			duration := c.Duration.String()
+			switch {
+			case c.Granularity != NoGranularity:
+				duration = "1 " + c.Granularity.String()
+			case c.Duration == Unbounded:
+				duration = "unbounded"
+			}
+			fmt.Fprintf(&b, "  %d. %s\tstart %s\tduration %s\tpriority %d\n",
+				i+1, c.Period.GetIdentifier(), c.StartTime, duration, c.Priority)
+		}
+	}
+	if len(e.Excluded) > 0 {
+		fmt.Fprintf(&b, "Excluded:\n")
+		for _, x := range e.Excluded {
+			fmt.Fprintf(&b, "  %s\t%s\n", x.Period.GetIdentifier(), x.Reason)
+		}
+	}
+	return b.String()
+}
+
+// Explain reports which periods contain ts, how DefaultPolicy ranks them and
+// why the others were excluded.
+func Explain(ts time.Time, periods ...Period) Explanation {
+	return DefaultPolicy.Explain(ts, periods...)
+}
+
+// Explain reports which periods contain ts, how the policy ranks them and
+// why the others were excluded.
+func (p Policy) Explain(ts time.Time, periods ...Period) Explanation {
+	e := Explanation{Timestamp: ts}
+	candidates := candidatesAt(ts, periods)
+	next := 0
+	for i, x := range periods {
+		if next < len(candidates) && candidates[next].index == i {
+			for next < len(candidates) && candidates[next].index == i {
+				next++
+			}
+			continue
+		}
+		e.Excluded = append(e.Excluded, Exclusion{Period: x, Index: i, Reason: exclusionReason(x, ts)})
+	}
+
+	p.rank(candidates)
+	for _, x := range candidates {
+		e.Candidates = append(e.Candidates, Candidate{
+			Period:      x.Period,
+			Index:       x.index,
+			StartTime:   x.Period.GetStartTime(),
+			Duration:    specificity(x.Period),
+			Granularity: GetGranularity(x.Period),
+			Priority:    GetPriority(x.Period),
+		})
+	}
+	if len(candidates) > 1 {
+		e.DecidedBy = DecidedByInputOrder
+		if r, ok := p.decidingRule(candidates[0].Period, candidates[1].Period); ok {
+			e.DecidedBy = r.Name
+		}
+	}
+	return e
+}
+
+// rank sorts instances from most to least specific, keeping input order
+// among instances the policy cannot tell apart.
+func (p Policy) rank(instances []instance) {
+	sort.SliceStable(instances, func(i, j int) bool {
+		return p.Compare(instances[i].Period, instances[j].Period) < 0
+	})
+}
+
+// decidingRule returns the first rule of the policy that tells a and b
+// apart.
+func (p Policy) decidingRule(a, b Period) (Rule, bool) {
+	for _, r := range p.rules() {
+		if r.Compare(a, b) != 0 {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// exclusionReason returns why x does not contain ts.
+func exclusionReason(x Period, ts time.Time) ExclusionReason {
+	if _, ok := x.(Recurring); ok {
+		return NoOccurrence
+	}
+	// an open window with equal ends is empty, not inverted
+	if start, end := x.GetStartTime(), x.GetEndTime(); !start.IsZero() && !end.IsZero() && start.After(end) {
+		return InvertedBounds
+	}
+	if start := startOf(x); !start.IsZero() && start.After(ts) {
+		return NotStarted
+	}
+	return Ended
+}
@@ -0,0 +1,231 @@
+package msp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplain(t *testing.T) {
+	// use a static timestamp to make sure tests don't fail on slower systems or during a process pause
+	now := time.Now()
+	testCases := []struct {
+		testID     string
+		periods    []Period
+		winner     string
+		candidates []string
+		decidedBy  string
+		excluded   map[string]ExclusionReason
+	}{
+		{
+			testID:   "No choices",
+			periods:  []Period{},
+			excluded: map[string]ExclusionReason{},
+		},
+		{
+			testID: "Exclusion reasons",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(time.Minute),
+					EndTime:    now.Add(2 * time.Minute),
+					Identifier: "future",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-2 * time.Minute),
+					EndTime:    now,
+					Identifier: "past",
+				},
+				TimeWindow{
+					StartTime:  now.Add(time.Minute),
+					EndTime:    now.Add(-time.Minute),
+					Identifier: "inverted",
+				},
+				TimeWindow{
+					StartTime:  now,
+					EndTime:    now,
+					Identifier: "empty now",
+					Bounds:     Open,
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(-time.Minute),
+					Identifier: "empty before",
+					Bounds:     Open,
+				},
+				RecurringPeriod{
+					StartTime:  now.Add(time.Hour),
+					Duration:   time.Minute,
+					Identifier: "recurring",
+					Recurrence: Recurrence{Frequency: Daily},
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "current",
+				},
+			},
+			winner:     "current",
+			candidates: []string{"current"},
+			excluded: map[string]ExclusionReason{
+				"future":       NotStarted,
+				"past":         Ended,
+				"inverted":     InvertedBounds,
+				"empty now":    NotStarted,
+				"empty before": Ended,
+				"recurring":    NoOccurrence,
+			},
+		},
+		{
+			testID: "Decided by duration",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Hour),
+					EndTime:    now.Add(time.Hour),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "B",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Hour),
+					Identifier: "C",
+				},
+			},
+			winner:     "B",
+			candidates: []string{"B", "C", "A"},
+			decidedBy:  ShortestDuration.Name,
+			excluded:   map[string]ExclusionReason{},
+		},
+		{
+			testID: "Decided by priority",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Hour),
+					EndTime:    now.Add(time.Hour),
+					Identifier: "A",
+					Priority:   1,
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "B",
+				},
+			},
+			winner:     "A",
+			candidates: []string{"A", "B"},
+			decidedBy:  HighestPriority.Name,
+			excluded:   map[string]ExclusionReason{},
+		},
+		{
+			testID: "Decided by identifier",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "B",
+				},
+			},
+			winner:     "B",
+			candidates: []string{"B", "A"},
+			decidedBy:  LastIdentifier.Name,
+			excluded:   map[string]ExclusionReason{},
+		},
+		{
+			testID: "Decided by input order",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "A",
+				},
+			},
+			winner:     "A",
+			candidates: []string{"A", "A"},
+			decidedBy:  DecidedByInputOrder,
+			excluded:   map[string]ExclusionReason{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			e := Explain(now, tc.periods...)
+			winner, ok := e.Winner()
+			if ok != (tc.winner != "") {
+				t.Fatalf("Winner presence %v does not match expected %q", ok, tc.winner)
+			}
+			if ok && winner.GetIdentifier() != tc.winner {
+				t.Errorf("Winner '%s' does not match expected '%s'", winner.GetIdentifier(), tc.winner)
+			}
+			id, _ := MostSpecificPeriod(now, tc.periods...)
+			if id != tc.winner {
+				t.Errorf("Explained winner '%s' disagrees with MostSpecificPeriod '%s'", tc.winner, id)
+			}
+			var candidates []string
+			for _, c := range e.Candidates {
+				candidates = append(candidates, c.Period.GetIdentifier())
+			}
+			if !slicesEqual(candidates, tc.candidates) {
+				t.Errorf("Expected candidates %v but got %v", tc.candidates, candidates)
+			}
+			if e.DecidedBy != tc.decidedBy {
+				t.Errorf("Decided by '%s', expected '%s'", e.DecidedBy, tc.decidedBy)
+			}
+			if len(e.Excluded) != len(tc.excluded) {
+				t.Errorf("Expected %d exclusions but got %d", len(tc.excluded), len(e.Excluded))
+			}
+			for _, x := range e.Excluded {
+				if reason := tc.excluded[x.Period.GetIdentifier()]; reason != x.Reason {
+					t.Errorf("%s excluded as '%s', expected '%s'", x.Period.GetIdentifier(), x.Reason, reason)
+				}
+			}
+			if !strings.HasPrefix(e.String(), "Timestamp: ") {
+				t.Errorf("Unexpected report %q", e.String())
+			}
+		})
+	}
+}
+
+func TestExplainCalendarDuration(t *testing.T) {
+	newYork := loadLocation(t, "America/New_York")
+	// clocks skip an hour on March 10th 2024, so the day lasts 23h
+	ts := time.Date(2024, time.March, 10, 12, 0, 0, 0, newYork)
+	day := CalendarPeriod{Granularity: CalendarDay, Date: ts}
+	window := TimeWindow{
+		StartTime:  day.GetStartTime(),
+		EndTime:    day.GetStartTime().Add(23*time.Hour + 30*time.Minute),
+		Identifier: "window",
+	}
+	e := Explain(ts, day, window)
+	if len(e.Candidates) != 2 {
+		t.Fatalf("Expected 2 candidates but got %d", len(e.Candidates))
+	}
+	expected := []Candidate{
+		{Period: window, Index: 1, Duration: 23*time.Hour + 30*time.Minute, Granularity: NoGranularity},
+		{Period: day, Index: 0, Duration: 24 * time.Hour, Granularity: CalendarDay},
+	}
+	for i, c := range e.Candidates {
+		if c.Index != expected[i].Index || c.Duration != expected[i].Duration || c.Granularity != expected[i].Granularity {
+			t.Errorf("Candidate %d (%s, %v, %v) does not match expected (%s, %v, %v)", i+1,
+				c.Period.GetIdentifier(), c.Duration, c.Granularity,
+				expected[i].Period.GetIdentifier(), expected[i].Duration, expected[i].Granularity)
+		}
+	}
+	if e.DecidedBy != ShortestDuration.Name {
+		t.Errorf("DecidedBy %q does not match expected %q", e.DecidedBy, ShortestDuration.Name)
+	}
+	if s := e.String(); !strings.Contains(s, "duration 1 day") {
+		t.Errorf("Report does not show the nominal day:\n%s", s)
+	}
+}
@@ -0,0 +1,190 @@
+package msp
+
+import "time"
+
+// Compile-time interface checks.
+var (
+	_ Period      = TimeWindowOf[struct{}]{}
+	_ Prioritized = TimeWindowOf[struct{}]{}
+	_ Bounded     = TimeWindowOf[struct{}]{}
+	_ Period      = Segment[Period]{}
+)
+
+// TimeWindowOf is a TimeWindow carrying a payload of type T, so that the
+// winning period can be used directly without looking it up by identifier.
+type TimeWindowOf[T any] struct {
+	StartTime  time.Time
+	EndTime    time.Time
+	Identifier string
+	Priority   int
+	Bounds     Bounds
+	Value      T
+}
+
+// GetIdentifier returns the period's identifier string.
+func (p TimeWindowOf[T]) GetIdentifier() string {
+	return p.Identifier
+}
+
+// GetEndTime returns the period's end time.
+func (p TimeWindowOf[T]) GetEndTime() time.Time {
+	return p.EndTime
+}
+
+// GetStartTime returns the period's start time.
+func (p TimeWindowOf[T]) GetStartTime() time.Time {
+	return p.StartTime
+}
+
+// GetPriority returns the period's explicit priority.
+func (p TimeWindowOf[T]) GetPriority() int {
+	return p.Priority
+}
+
+// GetBounds returns whether the window contains its start and end times.
+func (p TimeWindowOf[T]) GetBounds() Bounds {
+	return p.Bounds
+}
+
+// Segment is a stretch of a timeline during which Period is the most
+// specific period. StartTime and EndTime are the bounds of the stretch,
+// which may be narrower than those of Period. A gap segment covers a
+// stretch where no period is active; its Period is the zero P.
+type Segment[P Period] struct {
+	StartTime time.Time
+	EndTime   time.Time
+	Period    P
+	Gap       bool
+}
+
+// GetIdentifier returns the identifier of the segment's period, or the
+// empty string for a gap.
+func (s Segment[P]) GetIdentifier() string {
+	if s.Gap {
+		return ""
+	}
+	return s.Period.GetIdentifier()
+}
+
+// GetEndTime returns the segment's exclusive end time.
+func (s Segment[P]) GetEndTime() time.Time {
+	return s.EndTime
+}
+
+// GetStartTime returns the segment's inclusive start time.
+func (s Segment[P]) GetStartTime() time.Time {
+	return s.StartTime
+}
+
+// GetPriority returns the priority of the segment's period, or 0 for a gap.
+func (s Segment[P]) GetPriority() int {
+	if s.Gap {
+		return 0
+	}
+	return GetPriority(s.Period)
+}
+
+// MostSpecific returns the most specific period containing ts under
+// DefaultPolicy. Unlike MostSpecificPeriod it returns the period itself
+// rather than its identifier; for a Recurring period, that is the series
+// and not the occurrence. If no period contains ts, the zero P and
+// ErrNoValidPeriods are returned.
+func MostSpecific[P Period](ts time.Time, periods ...P) (P, error) {
+	return MostSpecificBy(DefaultPolicy, ts, periods...)
+}
+
+// MostSpecificBy is like MostSpecific but ranks periods under policy.
+func MostSpecificBy[P Period](policy Policy, ts time.Time, periods ...P) (P, error) {
+	winner, ok := policy.winnerAt(ts, toPeriods(periods))
+	if !ok {
+		var zero P
+		return zero, ErrNoValidPeriods
+	}
+	return periods[winner.index], nil
+}
+
+// Timeline flattens periods into non-overlapping segments under
+// DefaultPolicy, each referring to the input period that wins across it.
+// Segments follow period instances rather than identifiers, so two periods
+// sharing an identifier produce separate segments, as do two occurrences of
+// a Recurring period. Stretches between periods where none is active are
+// returned as gap segments; use WithoutGaps to drop them. Recurring periods
+// without end are expanded up to the horizon described at NewResolver, where
+// the timeline stops; use TimelineBetween to choose the range instead.
+func Timeline[P Period](periods ...P) []Segment[P] {
+	return TimelineBy(DefaultPolicy, periods...)
+}
+
+// TimelineBy is like Timeline but ranks periods under policy.
+func TimelineBy[P Period](policy Policy, periods ...P) (out []Segment[P]) {
+	policy.NewResolver(toPeriods(periods)...).segments(func(start, end time.Time, winner instance) {
+		if winner.Period == nil {
+			// the stretches before the first and after the last period
+			// are unbounded and not worth reporting
+			if !start.IsZero() && !end.IsZero() {
+				out = append(out, Segment[P]{StartTime: start, EndTime: end, Gap: true})
+			}
+			return
+		}
+		out = append(out, Segment[P]{
+			StartTime: start,
+			EndTime:   end,
+			Period:    periods[winner.index],
+		})
+	})
+	return out
+}
+
+// TimelineBetween is like Timeline but only covers [from, to): segments are
+// clipped to the range, and stretches within it where no period is active
+// are returned as gap segments. Recurring periods are expanded within the
+// range, so series without end need no further bounding. If from is not
+// before to, no segments are returned.
+func TimelineBetween[P Period](from, to time.Time, periods ...P) []Segment[P] {
+	return TimelineBetweenBy(DefaultPolicy, from, to, periods...)
+}
+
+// TimelineBetweenBy is like TimelineBetween but ranks periods under policy.
+func TimelineBetweenBy[P Period](policy Policy, from, to time.Time, periods ...P) (out []Segment[P]) {
+	if !from.Before(to) {
+		return nil
+	}
+	r := policy.newResolver(expandBetween(from, to, toPeriods(periods)))
+	r.segments(func(start, end time.Time, winner instance) {
+		if start.IsZero() || start.Before(from) {
+			start = from
+		}
+		if end.IsZero() || end.After(to) {
+			end = to
+		}
+		if !start.Before(end) {
+			return
+		}
+		s := Segment[P]{StartTime: start, EndTime: end, Gap: winner.Period == nil}
+		if !s.Gap {
+			s.Period = periods[winner.index]
+		}
+		out = append(out, s)
+	})
+	return out
+}
+
+// WithoutGaps returns the segments that are not gaps.
+func WithoutGaps[P Period](segments []Segment[P]) []Segment[P] {
+	var out []Segment[P]
+	for _, s := range segments {
+		if !s.Gap {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// toPeriods converts a slice of a concrete period type to []Period.
+func toPeriods[P Period](periods []P) []Period {
+	out := make([]Period, len(periods))
+	for i, p := range periods {
+		out[i] = p
+	}
+	return out
+}
@@ -0,0 +1,138 @@
+package msp
+
+import (
+	"testing"
+	"time"
+)
+
+type rate struct {
+	cents int
+}
+
+func TestMostSpecific(t *testing.T) {
+	// use a static timestamp to make sure tests don't fail on slower systems or during a process pause
+	now := time.Now()
+	periods := []TimeWindowOf[rate]{
+		{
+			StartTime:  now.Add(-time.Hour),
+			EndTime:    now.Add(time.Hour),
+			Identifier: "standard",
+			Value:      rate{cents: 100},
+		},
+		{
+			StartTime:  now.Add(-time.Minute),
+			EndTime:    now.Add(time.Minute),
+			Identifier: "flash-sale",
+			Value:      rate{cents: 50},
+		},
+	}
+	testCases := []struct {
+		testID string
+		ts     time.Time
+		policy Policy
+		result int
+		err    error
+	}{
+		{
+			testID: "Shortest period wins",
+			ts:     now,
+			policy: DefaultPolicy,
+			result: 50,
+		},
+		{
+			testID: "Only one period active",
+			ts:     now.Add(30 * time.Minute),
+			policy: DefaultPolicy,
+			result: 100,
+		},
+		{
+			testID: "Custom policy",
+			ts:     now,
+			policy: NewPolicy(LongestDuration),
+			result: 100,
+		},
+		{
+			testID: "No period active",
+			ts:     now.Add(2 * time.Hour),
+			policy: DefaultPolicy,
+			result: 0,
+			err:    ErrNoValidPeriods,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			p, err := MostSpecificBy(tc.policy, tc.ts, periods...)
+			if err != tc.err {
+				t.Errorf("Error '%v' does not match expected '%v'", err, tc.err)
+			}
+			if p.Value.cents != tc.result {
+				t.Errorf("Value %d does not match expected %d", p.Value.cents, tc.result)
+			}
+		})
+	}
+
+	p, err := MostSpecific(now, periods...)
+	if err != nil || p.Identifier != "flash-sale" {
+		t.Errorf("Expected flash-sale but got %q (%v)", p.Identifier, err)
+	}
+}
+
+func TestMostSpecificRecurring(t *testing.T) {
+	start := time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC)
+	series := RecurringPeriod{
+		StartTime:  start,
+		Duration:   time.Hour,
+		Identifier: "standup",
+		Recurrence: Recurrence{Frequency: Daily},
+	}
+	p, err := MostSpecific[Period](start.AddDate(0, 0, 3).Add(30*time.Minute), series)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, ok := p.(RecurringPeriod); !ok {
+		t.Errorf("Expected the series to be returned, got %T", p)
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	now := time.Now()
+	periods := []TimeWindowOf[rate]{
+		{
+			StartTime:  now.Add(-time.Hour),
+			EndTime:    now.Add(time.Hour),
+			Identifier: "standard",
+			Value:      rate{cents: 100},
+		},
+		{
+			StartTime:  now.Add(-time.Minute),
+			EndTime:    now.Add(time.Minute),
+			Identifier: "flash-sale",
+			Value:      rate{cents: 50},
+		},
+		{
+			StartTime:  now.Add(2 * time.Hour),
+			EndTime:    now.Add(3 * time.Hour),
+			Identifier: "night",
+			Value:      rate{cents: 80},
+		},
+	}
+	expected := []Segment[TimeWindowOf[rate]]{
+		{StartTime: now.Add(-time.Hour), EndTime: now.Add(-time.Minute), Period: periods[0]},
+		{StartTime: now.Add(-time.Minute), EndTime: now.Add(time.Minute), Period: periods[1]},
+		{StartTime: now.Add(time.Minute), EndTime: now.Add(time.Hour), Period: periods[0]},
+		{StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour), Gap: true},
+		{StartTime: now.Add(2 * time.Hour), EndTime: now.Add(3 * time.Hour), Period: periods[2]},
+	}
+	timeline := Timeline(periods...)
+	if len(timeline) != len(expected) {
+		t.Fatalf("Time line had %d results, expected %d", len(timeline), len(expected))
+	}
+	for idx, segment := range timeline {
+		if segment != expected[idx] {
+			t.Errorf("Expected:\t%v\nHad:\t%v", expected[idx], segment)
+		}
+	}
+	if withoutGaps := WithoutGaps(timeline); len(withoutGaps) != len(expected)-1 {
+		t.Errorf("Expected %d segments without gaps, got %d", len(expected)-1, len(withoutGaps))
+	}
+}
@@ -0,0 +1,571 @@
+package msp
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// iCalendar time layouts.
+const (
+	icalDate     = "20060102"
+	icalDateTime = "20060102T150405"
+	icalUTC      = "20060102T150405Z"
+)
+
+// icalLineLength is the number of octets after which content lines are
+// folded.
+const icalLineLength = 75
+
+// icalWeekdays maps the weekday codes of RFC 5545 to time.Weekday.
+var icalWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// ICalendarOptions configures ParseICalendar. The zero value identifies
+// events by their SUMMARY and reads floating times in time.Local.
+type ICalendarOptions struct {
+	// IdentifierProperty names the property used as the identifier of an
+	// event, such as "SUMMARY" (the default) or "UID". Events without it
+	// fall back to their SUMMARY, then their UID.
+	IdentifierProperty string
+	// Location is used for floating times and dates, which carry neither a
+	// TZID nor a UTC designator. A nil Location is time.Local.
+	Location *time.Location
+}
+
+// ParseICalendar reads the VEVENTs of an RFC 5545 calendar as periods.
+// Events with an RRULE become a RecurringPeriod, all others a TimeWindow.
+// An event lasts from DTSTART to DTEND or for its DURATION; without either
+// it lasts one day if DTSTART is a date and is empty otherwise. Times with
+// a TZID are read in that location, so recurrences keep their wall-clock
+// time across daylight saving transitions. EXDATEs become the Exclude list
+// of the recurrence.
+//
+// Only the parts of an RRULE that Recurrence supports are accepted: FREQ
+// (DAILY, WEEKLY, MONTHLY or YEARLY), INTERVAL, COUNT, UNTIL, BYDAY,
+// BYMONTHDAY, WKST, and a BYMONTH naming the month of DTSTART. A YEARLY rule
+// with BYDAY or BYMONTHDAY must name that month, and WKST must be MO for a
+// WEEKLY rule with both INTERVAL and BYDAY. Other rule parts and
+// combinations are reported as errors rather than silently changing the
+// schedule.
+func ParseICalendar(r io.Reader, opts ICalendarOptions) ([]Period, error) {
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	lines, err := icalLines(r)
+	if err != nil {
+		return nil, err
+	}
+	periods := []Period{}
+	var event *icalEvent
+	depth := 0
+	for _, l := range lines {
+		switch {
+		case l.name == "BEGIN" && event == nil:
+			if strings.EqualFold(l.value, "VEVENT") {
+				event = &icalEvent{line: l.line, props: map[string][]icalLine{}}
+			}
+		case l.name == "BEGIN":
+			depth++
+		case l.name == "END" && event != nil && depth > 0:
+			depth--
+		case l.name == "END" && event != nil:
+			p, err := event.period(opts)
+			if err != nil {
+				return nil, fmt.Errorf("ical: event at line %d: %w", event.line, err)
+			}
+			periods = append(periods, p)
+			event = nil
+		case event != nil && depth == 0:
+			event.props[l.name] = append(event.props[l.name], l)
+		}
+	}
+	if event != nil {
+		return nil, fmt.Errorf("ical: event at line %d: missing END:VEVENT", event.line)
+	}
+	return periods, nil
+}
+
+// icalLine is an unfolded content line.
+type icalLine struct {
+	line   int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// icalLines reads the unfolded content lines of r. Continuation lines start
+// with a space or tab.
+func icalLines(r io.Reader) ([]icalLine, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	var raw []string
+	var numbers []int
+	n := 0
+	for s.Scan() {
+		n++
+		text := strings.TrimRight(s.Text(), "\r")
+		if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+			if len(raw) == 0 {
+				return nil, fmt.Errorf("ical: line %d: continuation without content line", n)
+			}
+			raw[len(raw)-1] += text[1:]
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		raw = append(raw, text)
+		numbers = append(numbers, n)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	lines := make([]icalLine, 0, len(raw))
+	for i, text := range raw {
+		l, err := parseICalLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("ical: line %d: %w", numbers[i], err)
+		}
+		l.line = numbers[i]
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// parseICalLine splits a content line into its name, parameters and value.
+// Parameter values may be quoted to contain ';', ':' and ','.
+func parseICalLine(text string) (icalLine, error) {
+	l := icalLine{params: map[string]string{}}
+	quoted := false
+	var fields []string
+	start := 0
+	for i, r := range text {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == ';':
+			fields = append(fields, text[start:i])
+			start = i + 1
+		case r == ':':
+			fields = append(fields, text[start:i])
+			l.value = text[i+1:]
+			l.name = strings.ToUpper(fields[0])
+			for _, f := range fields[1:] {
+				name, value, _ := strings.Cut(f, "=")
+				l.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+			}
+			return l, nil
+		}
+	}
+	return l, fmt.Errorf("malformed content line %q", text)
+}
+
+// icalEvent collects the properties of a VEVENT.
+type icalEvent struct {
+	line  int
+	props map[string][]icalLine
+}
+
+// get returns the first property named name.
+func (e *icalEvent) get(name string) (icalLine, bool) {
+	if l := e.props[name]; len(l) > 0 {
+		return l[0], true
+	}
+	return icalLine{}, false
+}
+
+// identifier returns the unescaped text of the first of the given
+// properties that is present.
+func (e *icalEvent) identifier(names ...string) string {
+	for _, name := range names {
+		if l, ok := e.get(name); ok && l.value != "" {
+			return icalText(l.value)
+		}
+	}
+	return ""
+}
+
+// period converts the event into a TimeWindow or RecurringPeriod.
+func (e *icalEvent) period(opts ICalendarOptions) (Period, error) {
+	id := e.identifier(strings.ToUpper(opts.IdentifierProperty), "SUMMARY", "UID")
+	dtstart, ok := e.get("DTSTART")
+	if !ok {
+		return nil, fmt.Errorf("missing DTSTART")
+	}
+	starts, allDay, err := icalTimes(dtstart, opts.Location)
+	if err != nil {
+		return nil, fmt.Errorf("DTSTART: %w", err)
+	}
+	start := starts[0]
+	end := start
+	if allDay {
+		end = start.AddDate(0, 0, 1)
+	}
+	if dtend, ok := e.get("DTEND"); ok {
+		ends, _, err := icalTimes(dtend, opts.Location)
+		if err != nil {
+			return nil, fmt.Errorf("DTEND: %w", err)
+		}
+		end = ends[0]
+	} else if duration, ok := e.get("DURATION"); ok {
+		days, d, err := icalDuration(duration.value)
+		if err != nil {
+			return nil, fmt.Errorf("DURATION: %w", err)
+		}
+		end = start.AddDate(0, 0, days).Add(d)
+	}
+	if end.Before(start) {
+		return nil, ErrEndAfterStart
+	}
+
+	rrule, ok := e.get("RRULE")
+	if !ok {
+		return TimeWindow{StartTime: start, EndTime: end, Identifier: id}, nil
+	}
+	recurrence, err := icalRecurrence(rrule.value, start)
+	if err != nil {
+		return nil, fmt.Errorf("RRULE: %w", err)
+	}
+	for _, exdate := range e.props["EXDATE"] {
+		excluded, _, err := icalTimes(exdate, opts.Location)
+		if err != nil {
+			return nil, fmt.Errorf("EXDATE: %w", err)
+		}
+		recurrence.Exclude = append(recurrence.Exclude, excluded...)
+	}
+	return RecurringPeriod{
+		StartTime:  start,
+		Duration:   end.Sub(start),
+		Identifier: id,
+		Recurrence: recurrence,
+	}, nil
+}
+
+// icalTimes parses the comma separated dates or date-times of a property.
+// allDay reports whether the values are dates, which start at midnight in
+// loc.
+func icalTimes(l icalLine, loc *time.Location) (times []time.Time, allDay bool, err error) {
+	if tzid := l.params["TZID"]; tzid != "" {
+		if loc, err = time.LoadLocation(strings.TrimPrefix(tzid, "/")); err != nil {
+			return nil, false, fmt.Errorf("unknown TZID %q", tzid)
+		}
+	}
+	allDay = strings.EqualFold(l.params["VALUE"], "DATE")
+	for _, value := range strings.Split(l.value, ",") {
+		t, date, err := icalTime(value, loc)
+		if err != nil {
+			return nil, false, err
+		}
+		allDay = allDay || date
+		times = append(times, t)
+	}
+	return times, allDay, nil
+}
+
+// icalTime parses a date, a UTC date-time or a date-time local to loc.
+func icalTime(value string, loc *time.Location) (t time.Time, date bool, err error) {
+	switch {
+	case len(value) == len(icalDate):
+		t, err = time.ParseInLocation(icalDate, value, loc)
+		date = true
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse(icalUTC, value)
+	default:
+		t, err = time.ParseInLocation(icalDateTime, value, loc)
+	}
+	if err != nil {
+		return t, date, fmt.Errorf("invalid date or time %q", value)
+	}
+	return t, date, nil
+}
+
+// icalDuration parses an RFC 5545 duration such as "P1W", "P1DT2H" or
+// "-PT15M". Weeks and days are returned separately as nominal days so that
+// they can be added in the calendar of the start time.
+func icalDuration(value string) (days int, d time.Duration, err error) {
+	invalid := fmt.Errorf("invalid duration %q", value)
+	sign := 1
+	rest := value
+	if rest != "" && (rest[0] == '+' || rest[0] == '-') {
+		if rest[0] == '-' {
+			sign = -1
+		}
+		rest = rest[1:]
+	}
+	if !strings.HasPrefix(rest, "P") || len(rest) == 1 {
+		return 0, 0, invalid
+	}
+	rest = rest[1:]
+	inTime := false
+	for rest != "" {
+		if rest[0] == 'T' {
+			inTime = true
+			rest = rest[1:]
+			continue
+		}
+		i := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if i <= 0 {
+			return 0, 0, invalid
+		}
+		n, _ := strconv.Atoi(rest[:i])
+		switch unit := rest[i]; {
+		case unit == 'W' && !inTime:
+			days += 7 * n
+		case unit == 'D' && !inTime:
+			days += n
+		case unit == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case unit == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case unit == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, 0, invalid
+		}
+		rest = rest[i+1:]
+	}
+	return sign * days, time.Duration(sign) * d, nil
+}
+
+// icalRecurrence converts an RRULE into a Recurrence for a series starting
+// at start. Rules that Recurrence would expand differently are reported as
+// errors.
+func icalRecurrence(rule string, start time.Time) (Recurrence, error) {
+	var r Recurrence
+	byMonth := false
+	weekStart := "MO"
+	for _, part := range strings.Split(rule, ";") {
+		name, value, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			switch strings.ToUpper(value) {
+			case "DAILY":
+				r.Frequency = Daily
+			case "WEEKLY":
+				r.Frequency = Weekly
+			case "MONTHLY":
+				r.Frequency = Monthly
+			case "YEARLY":
+				r.Frequency = Yearly
+			default:
+				return r, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = icalNumber(name, value)
+		case "COUNT":
+			r.Count, err = icalNumber(name, value)
+		case "UNTIL":
+			r.Until, _, err = icalTime(value, start.Location())
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				w, err := icalWeekdayNum(day)
+				if err != nil {
+					return r, err
+				}
+				r.ByDay = append(r.ByDay, w)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return r, fmt.Errorf("invalid BYMONTHDAY %q", day)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			if value != strconv.Itoa(int(start.Month())) {
+				return r, fmt.Errorf("unsupported BYMONTH %q, only the month of DTSTART is supported", value)
+			}
+			byMonth = true
+		case "WKST":
+			weekStart = strings.ToUpper(value)
+			if _, ok := icalWeekdays[weekStart]; !ok {
+				return r, fmt.Errorf("invalid WKST %q", value)
+			}
+		default:
+			return r, fmt.Errorf("unsupported rule part %q", name)
+		}
+		if err != nil {
+			return r, err
+		}
+	}
+	if r.Frequency == Once {
+		return r, fmt.Errorf("missing FREQ")
+	}
+	// Yearly recurrences only look at the month of the start
+	if r.Frequency == Yearly && (len(r.ByDay) > 0 || len(r.ByMonthDay) > 0) && !byMonth {
+		return r, fmt.Errorf("unsupported BYDAY or BYMONTHDAY in a YEARLY rule without BYMONTH")
+	}
+	// weeks of Weekly recurrences start on Monday, which only matters when
+	// some weeks are skipped
+	if r.Frequency == Weekly && r.Interval > 1 && len(r.ByDay) > 0 && weekStart != "MO" {
+		return r, fmt.Errorf("unsupported WKST %q, only MO is supported for WEEKLY rules with INTERVAL and BYDAY", weekStart)
+	}
+	return r, nil
+}
+
+// icalNumber parses the positive integer value of a rule part.
+func icalNumber(name, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return n, nil
+}
+
+// icalWeekdayNum parses a BYDAY value such as "MO", "2TU" or "-1FR".
+func icalWeekdayNum(value string) (WeekdayNum, error) {
+	if len(value) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+	weekday, ok := icalWeekdays[strings.ToUpper(value[len(value)-2:])]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+	w := WeekdayNum{Weekday: weekday}
+	if prefix := value[:len(value)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+		}
+		w.N = n
+	}
+	return w, nil
+}
+
+// icalText unescapes a TEXT value.
+func icalText(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+// ICalendarExportOptions configures WriteICalendar. The zero value writes
+// the timeline of all periods under DefaultPolicy, stamped with the current
+// time.
+type ICalendarExportOptions struct {
+	// Policy resolves the timeline. The zero Policy is DefaultPolicy.
+	Policy Policy
+	// From and To limit the exported range. A zero time extends the range
+	// to the earliest start or latest end of the periods respectively.
+	From time.Time
+	To   time.Time
+	// Periods adds the input periods as events next to the timeline.
+	Periods bool
+	// Name is the calendar name shown by calendar applications.
+	Name string
+	// Stamp is the DTSTAMP of every event. The zero time means time.Now.
+	Stamp time.Time
+}
+
+// WriteICalendar writes the resolved timeline of the periods as an RFC 5545
+// calendar with one VEVENT per segment, categorized as "timeline", so that
+// calendar applications show which period applies when. With opts.Periods
+// set, the input periods follow as transparent events categorized as
+// "period", recurring periods as their individual occurrences. Periods
+// unbounded towards either side are cut at the edges of the range, and
+// nothing but the empty calendar is written if the range cannot be
+// determined, such as for periods without any bounds.
+//
+// Every event carries a UID derived from its category, identifier, start
+// and end, so that subscribers see unchanged events as the same event
+// across exports.
+func WriteICalendar(w io.Writer, opts ICalendarExportOptions, periods ...Period) error {
+	stamp := opts.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	var b strings.Builder
+	line := func(name, value string) {
+		b.WriteString(icalFold(name + ":" + value))
+	}
+	event := func(category, id string, start, end time.Time, transparent bool) {
+		line("BEGIN", "VEVENT")
+		line("UID", icalUID(category, id, start, end))
+		line("DTSTAMP", stamp.UTC().Format(icalUTC))
+		line("DTSTART", start.UTC().Format(icalUTC))
+		line("DTEND", end.UTC().Format(icalUTC))
+		line("SUMMARY", icalEscape(id))
+		line("CATEGORIES", category)
+		if transparent {
+			line("TRANSP", "TRANSPARENT")
+		}
+		line("END", "VEVENT")
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//taigrr//most-specific-period//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if opts.Name != "" {
+		line("X-WR-CALNAME", icalEscape(opts.Name))
+	}
+	if from, to, ok := chartRange(opts.From, opts.To, periods); ok {
+		for _, s := range WithoutGaps(TimelineBetweenBy(opts.Policy, from, to, periods...)) {
+			event("timeline", s.GetIdentifier(), s.StartTime, s.EndTime, false)
+		}
+		if opts.Periods {
+			for _, x := range expandBetween(from, to, periods) {
+				if !nonEmpty(x) {
+					continue
+				}
+				start, end := x.GetStartTime(), x.GetEndTime()
+				if start.IsZero() || start.Before(from) {
+					start = from
+				}
+				if end.IsZero() || end.After(to) {
+					end = to
+				}
+				event("period", x.GetIdentifier(), start, end, true)
+			}
+		}
+	}
+	line("END", "VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// icalUID returns a UID that is stable for an event with the same category,
+// identifier, start and end.
+func icalUID(category, id string, start, end time.Time) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%d", category, id, start.UnixNano(), end.UnixNano())
+	return fmt.Sprintf("%016x@most-specific-period", h.Sum64())
+}
+
+// icalEscape escapes a TEXT value.
+func icalEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(value)
+}
+
+// icalFold terminates a content line with CRLF, folding it so that no line
+// exceeds icalLineLength octets without splitting UTF-8 sequences.
+func icalFold(line string) string {
+	var b strings.Builder
+	limit := icalLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = icalLineLength - 1
+	}
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
@@ -0,0 +1,347 @@
+package msp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendar wraps events in a VCALENDAR with CRLF line endings.
+func calendar(events ...string) string {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//msp//test//EN"}
+	for _, e := range events {
+		lines = append(lines, "BEGIN:VEVENT")
+		lines = append(lines, strings.Split(e, "\n")...)
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func TestParseICalendar(t *testing.T) {
+	day := func(d, hour int) time.Time {
+		return time.Date(2024, time.June, d, hour, 0, 0, 0, time.UTC)
+	}
+	testCases := []struct {
+		testID string
+		input  string
+		opts   ICalendarOptions
+		result []Period
+	}{
+		{
+			testID: "Empty calendar",
+			input:  calendar(),
+			result: []Period{},
+		},
+		{
+			testID: "UTC event with DTEND",
+			input:  calendar("UID:1@example.com\nSUMMARY:Launch\nDTSTART:20240603T090000Z\nDTEND:20240603T170000Z"),
+			result: []Period{TimeWindow{StartTime: day(3, 9), EndTime: day(3, 17), Identifier: "Launch"}},
+		},
+		{
+			testID: "Identifier from UID",
+			input:  calendar("UID:1@example.com\nSUMMARY:Launch\nDTSTART:20240603T090000Z\nDTEND:20240603T170000Z"),
+			opts:   ICalendarOptions{IdentifierProperty: "uid"},
+			result: []Period{TimeWindow{StartTime: day(3, 9), EndTime: day(3, 17), Identifier: "1@example.com"}},
+		},
+		{
+			testID: "Missing SUMMARY falls back to UID",
+			input:  calendar("UID:1@example.com\nDTSTART:20240603T090000Z\nDTEND:20240603T170000Z"),
+			result: []Period{TimeWindow{StartTime: day(3, 9), EndTime: day(3, 17), Identifier: "1@example.com"}},
+		},
+		{
+			testID: "Folded and escaped SUMMARY",
+			input:  calendar("SUMMARY:Sales\\, Q3\r\n  review\nDTSTART:20240603T090000Z\nDTEND:20240603T170000Z"),
+			result: []Period{TimeWindow{StartTime: day(3, 9), EndTime: day(3, 17), Identifier: "Sales, Q3 review"}},
+		},
+		{
+			testID: "DURATION",
+			input:  calendar("SUMMARY:Sprint\nDTSTART:20240603T090000Z\nDURATION:P1DT2H30M"),
+			result: []Period{TimeWindow{StartTime: day(3, 9), EndTime: day(4, 11).Add(30 * time.Minute), Identifier: "Sprint"}},
+		},
+		{
+			testID: "All-day event lasts one day",
+			input:  calendar("SUMMARY:Holiday\nDTSTART;VALUE=DATE:20240603"),
+			opts:   ICalendarOptions{Location: time.UTC},
+			result: []Period{TimeWindow{StartTime: day(3, 0), EndTime: day(4, 0), Identifier: "Holiday"}},
+		},
+		{
+			testID: "Floating time in Location",
+			input:  calendar("SUMMARY:Standup\nDTSTART:20240603T090000\nDTEND:20240603T091500"),
+			opts:   ICalendarOptions{Location: time.UTC},
+			result: []Period{TimeWindow{StartTime: day(3, 9), EndTime: day(3, 9).Add(15 * time.Minute), Identifier: "Standup"}},
+		},
+		{
+			testID: "Nested components are ignored",
+			input:  calendar("SUMMARY:Launch\nDTSTART:20240603T090000Z\nDTEND:20240603T170000Z\nBEGIN:VALARM\nTRIGGER:-PT15M\nDESCRIPTION:Reminder\nEND:VALARM"),
+			result: []Period{TimeWindow{StartTime: day(3, 9), EndTime: day(3, 17), Identifier: "Launch"}},
+		},
+		{
+			testID: "Weekly RRULE with EXDATE",
+			input:  calendar("SUMMARY:Standup\nDTSTART:20240603T090000Z\nDTEND:20240603T091500Z\nRRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;UNTIL=20240630T000000Z;WKST=MO\nEXDATE:20240605T090000Z,20240617T090000Z"),
+			result: []Period{RecurringPeriod{
+				StartTime:  day(3, 9),
+				Duration:   15 * time.Minute,
+				Identifier: "Standup",
+				Recurrence: Recurrence{
+					Frequency: Weekly,
+					Interval:  2,
+					ByDay:     []WeekdayNum{{Weekday: time.Monday}, {Weekday: time.Wednesday}},
+					Until:     day(30, 0),
+					Exclude:   []time.Time{day(5, 9), day(17, 9)},
+				},
+			}},
+		},
+		{
+			testID: "Monthly RRULE on the last Friday",
+			input:  calendar("SUMMARY:Review\nDTSTART:20240628T140000Z\nDURATION:PT1H\nRRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=6"),
+			result: []Period{RecurringPeriod{
+				StartTime:  day(28, 14),
+				Duration:   time.Hour,
+				Identifier: "Review",
+				Recurrence: Recurrence{Frequency: Monthly, ByDay: []WeekdayNum{{Weekday: time.Friday, N: -1}}, Count: 6},
+			}},
+		},
+		{
+			testID: "Yearly RRULE in the month of DTSTART",
+			input:  calendar("SUMMARY:Anniversary\nDTSTART;VALUE=DATE:20240603\nRRULE:FREQ=YEARLY;BYMONTH=6;BYMONTHDAY=3"),
+			opts:   ICalendarOptions{Location: time.UTC},
+			result: []Period{RecurringPeriod{
+				StartTime:  day(3, 0),
+				Duration:   24 * time.Hour,
+				Identifier: "Anniversary",
+				Recurrence: Recurrence{Frequency: Yearly, ByMonthDay: []int{3}},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			periods, err := ParseICalendar(strings.NewReader(tc.input), tc.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(periods, tc.result) {
+				t.Errorf("Result %v does not match expected %v", periods, tc.result)
+			}
+		})
+	}
+}
+
+func TestParseICalendarErrors(t *testing.T) {
+	testCases := []struct {
+		testID string
+		input  string
+		err    string
+	}{
+		{
+			testID: "Missing DTSTART",
+			input:  calendar("SUMMARY:Launch\nDTEND:20240603T170000Z"),
+			err:    "missing DTSTART",
+		},
+		{
+			testID: "End before start",
+			input:  calendar("SUMMARY:Launch\nDTSTART:20240603T170000Z\nDTEND:20240603T090000Z"),
+			err:    ErrEndAfterStart.Error(),
+		},
+		{
+			testID: "Unknown TZID",
+			input:  calendar("SUMMARY:Launch\nDTSTART;TZID=Nowhere/Special:20240603T090000\nDURATION:PT1H"),
+			err:    "unknown TZID",
+		},
+		{
+			testID: "Invalid DURATION",
+			input:  calendar("SUMMARY:Launch\nDTSTART:20240603T090000Z\nDURATION:P1H"),
+			err:    "invalid duration",
+		},
+		{
+			testID: "Unsupported rule part",
+			input:  calendar("SUMMARY:Launch\nDTSTART:20240603T090000Z\nDURATION:PT1H\nRRULE:FREQ=DAILY;BYHOUR=9,17"),
+			err:    "unsupported rule part",
+		},
+		{
+			testID: "Unsupported FREQ",
+			input:  calendar("SUMMARY:Launch\nDTSTART:20240603T090000Z\nDURATION:PT1H\nRRULE:FREQ=HOURLY"),
+			err:    "unsupported FREQ",
+		},
+		{
+			testID: "YEARLY with BYDAY but without BYMONTH",
+			input:  calendar("SUMMARY:Launch\nDTSTART:20240101T090000Z\nDURATION:PT1H\nRRULE:FREQ=YEARLY;BYDAY=MO;COUNT=10"),
+			err:    "without BYMONTH",
+		},
+		{
+			testID: "YEARLY with BYMONTHDAY but without BYMONTH",
+			input:  calendar("SUMMARY:Launch\nDTSTART:20240101T090000Z\nDURATION:PT1H\nRRULE:FREQ=YEARLY;BYMONTHDAY=1,15"),
+			err:    "without BYMONTH",
+		},
+		{
+			testID: "WKST other than Monday",
+			input:  calendar("SUMMARY:Launch\nDTSTART:19970805T090000Z\nDURATION:PT1H\nRRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU"),
+			err:    "unsupported WKST",
+		},
+		{
+			testID: "Unterminated event",
+			input:  "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Launch\r\n",
+			err:    "missing END:VEVENT",
+		},
+		{
+			testID: "Malformed line",
+			input:  "BEGIN:VCALENDAR\r\nnot a content line\r\n",
+			err:    "malformed content line",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			_, err := ParseICalendar(strings.NewReader(tc.input), ICalendarOptions{})
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Error %v does not match expected %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestParseICalendarMostSpecificPeriod(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	input := calendar(
+		"UID:office\nSUMMARY:Office hours\nDTSTART;TZID=Europe/Berlin:20240325T090000\nDTEND;TZID=Europe/Berlin:20240325T170000\nRRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR\nEXDATE;TZID=Europe/Berlin:20240401T090000",
+		"UID:quarter\nSUMMARY:Q2\nDTSTART;VALUE=DATE:20240401\nDTEND;VALUE=DATE:20240701",
+	)
+	periods, err := ParseICalendar(strings.NewReader(input), ICalendarOptions{Location: loc})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	testCases := []struct {
+		testID string
+		ts     time.Time
+		result string
+	}{
+		{testID: "Office hours after the DST change", ts: time.Date(2024, time.April, 2, 9, 30, 0, 0, loc), result: "Office hours"},
+		{testID: "Excluded occurrence", ts: time.Date(2024, time.April, 1, 9, 30, 0, 0, loc), result: "Q2"},
+		{testID: "Evening", ts: time.Date(2024, time.April, 2, 18, 0, 0, 0, loc), result: "Q2"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			id, err := MostSpecificPeriod(tc.ts, periods...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if id != tc.result {
+				t.Errorf("Result %q does not match expected %q", id, tc.result)
+			}
+		})
+	}
+}
+
+func TestWriteICalendar(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.June, d, 0, 0, 0, 0, time.UTC)
+	}
+	periods := []Period{
+		TimeWindow{StartTime: day(1), EndTime: day(30), Identifier: "June"},
+		TimeWindow{StartTime: day(10), EndTime: day(12), Identifier: "Sale; 10% off, all stores"},
+		RecurringPeriod{StartTime: day(3), Duration: time.Hour, Identifier: "Standup", Recurrence: Recurrence{Frequency: Weekly, Count: 2}},
+	}
+	testCases := []struct {
+		testID  string
+		periods []Period
+		opts    ICalendarExportOptions
+		result  []Period
+	}{
+		{
+			testID:  "Timeline",
+			periods: periods,
+			opts:    ICalendarExportOptions{Name: "Which rate applies when, across all of our stores and warehouses"},
+			result: []Period{
+				TimeWindow{StartTime: day(1), EndTime: day(3), Identifier: "June"},
+				TimeWindow{StartTime: day(3), EndTime: day(3).Add(time.Hour), Identifier: "Standup"},
+				TimeWindow{StartTime: day(3).Add(time.Hour), EndTime: day(10), Identifier: "June"},
+				TimeWindow{StartTime: day(10), EndTime: day(10).Add(time.Hour), Identifier: "Standup"},
+				TimeWindow{StartTime: day(10).Add(time.Hour), EndTime: day(12), Identifier: "Sale; 10% off, all stores"},
+				TimeWindow{StartTime: day(12), EndTime: day(30), Identifier: "June"},
+			},
+		},
+		{
+			testID:  "Limited range with periods",
+			periods: periods,
+			opts:    ICalendarExportOptions{From: day(9), To: day(11), Periods: true},
+			result: []Period{
+				TimeWindow{StartTime: day(9), EndTime: day(10), Identifier: "June"},
+				TimeWindow{StartTime: day(10), EndTime: day(10).Add(time.Hour), Identifier: "Standup"},
+				TimeWindow{StartTime: day(10).Add(time.Hour), EndTime: day(11), Identifier: "Sale; 10% off, all stores"},
+				TimeWindow{StartTime: day(9), EndTime: day(11), Identifier: "June"},
+				TimeWindow{StartTime: day(10), EndTime: day(11), Identifier: "Sale; 10% off, all stores"},
+				TimeWindow{StartTime: day(10), EndTime: day(10).Add(time.Hour), Identifier: "Standup"},
+			},
+		},
+		{
+			testID:  "No bounds",
+			periods: []Period{TimeWindow{Identifier: "always"}},
+			opts:    ICalendarExportOptions{Periods: true},
+			result:  []Period{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			tc.opts.Stamp = day(1)
+			var b strings.Builder
+			if err := WriteICalendar(&b, tc.opts, tc.periods...); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, line := range strings.SplitAfter(b.String(), "\r\n") {
+				if len(line) > icalLineLength+2 {
+					t.Errorf("Line %q exceeds %d octets", line, icalLineLength)
+				}
+			}
+			parsed, err := ParseICalendar(strings.NewReader(b.String()), ICalendarOptions{Location: time.UTC})
+			if err != nil {
+				t.Fatalf("Output does not parse: %v\n%s", err, b.String())
+			}
+			if !reflect.DeepEqual(parsed, tc.result) {
+				t.Errorf("Result %v does not match expected %v", parsed, tc.result)
+			}
+		})
+	}
+}
+
+func TestWriteICalendarStableUIDs(t *testing.T) {
+	start := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	june := TimeWindow{StartTime: start, EndTime: start.AddDate(0, 1, 0), Identifier: "June"}
+	sale := TimeWindow{StartTime: start.AddDate(0, 0, 9), EndTime: start.AddDate(0, 0, 11), Identifier: "Sale"}
+	late := TimeWindow{StartTime: start.AddDate(0, 0, 20), EndTime: start.AddDate(0, 0, 21), Identifier: "Late sale"}
+	uids := func(periods ...Period) map[string]string {
+		var b strings.Builder
+		WriteICalendar(&b, ICalendarExportOptions{Name: "Rates"}, periods...)
+		out := map[string]string{}
+		var uid string
+		for _, line := range strings.Split(b.String(), "\r\n") {
+			switch {
+			case strings.HasPrefix(line, "UID:"):
+				uid = strings.TrimPrefix(line, "UID:")
+			case strings.HasPrefix(line, "DTSTART:"):
+				out[strings.TrimPrefix(line, "DTSTART:")] = uid
+			}
+		}
+		return out
+	}
+	before := uids(june, sale)
+	after := uids(june, sale, late)
+	testCases := []struct {
+		testID string
+		start  string
+		same   bool
+	}{
+		{testID: "Unchanged segment", start: "20240601T000000Z", same: true},
+		{testID: "Unchanged sale", start: "20240610T000000Z", same: true},
+		{testID: "Segment cut short by the late sale", start: "20240612T000000Z", same: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			if before[tc.start] == "" || (before[tc.start] == after[tc.start]) != tc.same {
+				t.Errorf("UID %q before does not match expected sameness %v with %q after", before[tc.start], tc.same, after[tc.start])
+			}
+		})
+	}
+}
@@ -1,62 +1,60 @@
 package msp
 
 import (
-	"sort"
+	"math"
 	"time"
 )
 
 // MostSpecificPeriod returns the identifier of the shortest-duration period
-// that contains timestamp ts. When multiple periods share the shortest
+// that contains timestamp ts. Periods implementing Prioritized are ranked by
+// priority first, highest winning. Bounded periods are more specific than
+// periods without a start or end time. When multiple periods share the shortest
 // duration, the one with the latest start time wins; if start times also
 // match, the lexicographically last identifier is returned.
 func MostSpecificPeriod(ts time.Time, periods ...Period) (id string, err error) {
-	// Filter to get only valid periods here
-	periods = ValidTimePeriods(ts, periods...)
-	if len(periods) == 0 {
+	return DefaultPolicy.MostSpecificPeriod(ts, periods...)
+}
+
+// MostSpecificPeriod returns the identifier of the period containing ts that
+// ranks highest under the policy. If no period contains ts,
+// ErrNoValidPeriods is returned.
+func (p Policy) MostSpecificPeriod(ts time.Time, periods ...Period) (id string, err error) {
+	winner, ok := p.winnerAt(ts, periods)
+	if !ok {
 		return "", ErrNoValidPeriods
 	}
-	// find the shortest duration
-	d, _ := GetDuration(periods[0].GetStartTime(), periods[0].GetEndTime())
-	for _, x := range periods {
-		p, err := GetDuration(x.GetStartTime(), x.GetEndTime())
-		if err == nil && p < d {
-			d = p
-		}
-	}
-	// find all periods with this shortest duration
-	var matchingDurations []Period
-	for _, x := range periods {
-		p, err := GetDuration(x.GetStartTime(), x.GetEndTime())
-		if err == nil && p == d {
-			matchingDurations = append(matchingDurations, x)
-		}
-	}
-	// Find the newest time a period starts
-	newest := matchingDurations[0].GetStartTime()
-	for _, x := range matchingDurations {
-		if x.GetStartTime().After(newest) {
-			newest = x.GetStartTime()
-		}
+	return winner.GetIdentifier(), nil
+}
+
+// winnerAt returns the highest ranked instance containing ts. Instances the
+// policy cannot tell apart are resolved in favor of the earliest input. ok is
+// false when no period contains ts.
+func (p Policy) winnerAt(ts time.Time, periods []Period) (winner instance, ok bool) {
+	candidates := candidatesAt(ts, periods)
+	if len(candidates) == 0 {
+		return instance{}, false
 	}
-	// Determine whichever of these periods have the same start time in addition to duration
-	var matchingDurationsAndStartTimes []Period
-	for _, x := range matchingDurations {
-		if x.GetStartTime() == newest {
-			matchingDurationsAndStartTimes = append(matchingDurationsAndStartTimes, x)
+	winner = candidates[0]
+	for _, x := range candidates[1:] {
+		if p.Compare(x.Period, winner.Period) < 0 {
+			winner = x
 		}
 	}
-	// Finally, return the period with the 'last' name lexicographically
-	var identifiers []string
-	for _, x := range matchingDurationsAndStartTimes {
-		identifiers = append(identifiers, x.GetIdentifier())
-	}
-	sort.Strings(identifiers)
-	return identifiers[len(identifiers)-1], nil
+	return winner, true
 }
 
+// Unbounded is the duration reported by GetDuration for a period without a
+// start or end time.
+const Unbounded time.Duration = math.MaxInt64
+
 // GetDuration returns the duration between start and end. If start is after
-// end, ErrEndAfterStart is returned alongside the (negative) duration.
+// end, ErrEndAfterStart is returned alongside the (negative) duration. A zero
+// start or end time leaves the period unbounded on that side, and Unbounded
+// is returned.
 func GetDuration(start time.Time, end time.Time) (dur time.Duration, err error) {
+	if start.IsZero() || end.IsZero() {
+		return Unbounded, nil
+	}
 	if start.After(end) {
 		err = ErrEndAfterStart
 	}
@@ -64,16 +62,86 @@ func GetDuration(start time.Time, end time.Time) (dur time.Duration, err error)
 	return dur, err
 }
 
-// ValidTimePeriods filters periods to those whose start time is at or before
-// ts and whose end time is strictly after ts.
+// ValidTimePeriods filters periods to those containing ts: by default those
+// whose start time is at or before ts and whose end time is strictly after
+// ts, or as specified by the Bounds of periods implementing Bounded. A zero
+// start or end time is unbounded and always satisfies its side of the check.
+// Recurring periods are replaced by their occurrence containing ts, if any.
 func ValidTimePeriods(ts time.Time, periods ...Period) []Period {
 	var valid []Period
-	for _, p := range periods {
-		start := p.GetStartTime()
-		end := p.GetEndTime()
-		if (start.Before(ts) || start.Equal(ts)) && (end.After(ts)) {
-			valid = append(valid, p)
-		}
+	for _, x := range candidatesAt(ts, periods) {
+		valid = append(valid, x.Period)
 	}
 	return valid
 }
+
+// RankedPeriods returns the periods containing ts ordered from most to least
+// specific under DefaultPolicy, so the first entry is the period
+// MostSpecificPeriod picks and the rest form its fallback chain. Recurring
+// periods are replaced by their occurrence containing ts.
+func RankedPeriods(ts time.Time, periods ...Period) []Period {
+	return DefaultPolicy.RankedPeriods(ts, periods...)
+}
+
+// RankedPeriods returns the periods containing ts ordered from most to least
+// specific under the policy.
+func (p Policy) RankedPeriods(ts time.Time, periods ...Period) []Period {
+	candidates := candidatesAt(ts, periods)
+	p.rank(candidates)
+	var ranked []Period
+	for _, x := range candidates {
+		ranked = append(ranked, x.Period)
+	}
+	return ranked
+}
+
+// TopN returns at most the first n entries of RankedPeriods.
+func TopN(ts time.Time, n int, periods ...Period) []Period {
+	return DefaultPolicy.TopN(ts, n, periods...)
+}
+
+// TopN returns at most the first n entries of the policy's RankedPeriods.
+func (p Policy) TopN(ts time.Time, n int, periods ...Period) []Period {
+	ranked := p.RankedPeriods(ts, periods...)
+	if n < len(ranked) {
+		ranked = ranked[:max(n, 0)]
+	}
+	return ranked
+}
+
+// candidatesAt returns the instances of periods containing ts, in input
+// order.
+func candidatesAt(ts time.Time, periods []Period) []instance {
+	var out []instance
+	for _, x := range expandAt(ts, periods) {
+		if contains(x, ts) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// contains reports whether ts falls within p.
+func contains(p Period, ts time.Time) bool {
+	start := startOf(p)
+	end := endOf(p)
+	return (start.IsZero() || !start.After(ts)) && (end.IsZero() || end.After(ts))
+}
+
+// nonEmpty reports whether p contains at least one instant.
+func nonEmpty(p Period) bool {
+	start := startOf(p)
+	end := endOf(p)
+	return start.IsZero() || end.IsZero() || start.Before(end)
+}
+
+// openEnds returns how many of p's bounds are unset.
+func openEnds(p Period) (n int) {
+	if p.GetStartTime().IsZero() {
+		n++
+	}
+	if p.GetEndTime().IsZero() {
+		n++
+	}
+	return n
+}
@@ -35,6 +35,20 @@ func TestGetDuration(t *testing.T) {
 			dur:    -5 * time.Minute,
 			err:    ErrEndAfterStart,
 		},
+		{
+			testID: "No start time",
+			start:  time.Time{},
+			end:    now,
+			dur:    Unbounded,
+			err:    nil,
+		},
+		{
+			testID: "No end time",
+			start:  now,
+			end:    time.Time{},
+			dur:    Unbounded,
+			err:    nil,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.testID, func(t *testing.T) {
@@ -145,6 +159,49 @@ func TestValidTimePeriods(t *testing.T) {
 			},
 			count: 2,
 		},
+		{
+			testID: "No start time",
+			ts:     now,
+			periods: []Period{
+				TimeWindow{
+					EndTime:    now.Add(time.Minute),
+					Identifier: "A",
+				},
+			},
+			count: 1,
+		},
+		{
+			testID: "No end time",
+			ts:     now,
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					Identifier: "A",
+				},
+			},
+			count: 1,
+		},
+		{
+			testID: "No end time, not started yet",
+			ts:     now,
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(time.Minute),
+					Identifier: "A",
+				},
+			},
+			count: 0,
+		},
+		{
+			testID: "Unbounded on both sides",
+			ts:     now,
+			periods: []Period{
+				TimeWindow{
+					Identifier: "A",
+				},
+			},
+			count: 1,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.testID, func(t *testing.T) {
@@ -306,3 +363,261 @@ func TestMostSpecificPeriod(t *testing.T) {
 		})
 	}
 }
+
+func TestMostSpecificPeriodPriority(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		ts      time.Time
+		testID  string
+		result  string
+		periods []Period
+	}{
+		{
+			testID: "Longer override with higher priority wins",
+			ts:     now,
+			result: "promo",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "weekday",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Hour),
+					EndTime:    now.Add(time.Hour),
+					Identifier: "promo",
+					Priority:   1,
+				},
+			},
+		},
+		{
+			testID: "Equal priority falls back to duration",
+			ts:     now,
+			result: "B",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Hour),
+					EndTime:    now.Add(time.Hour),
+					Identifier: "A",
+					Priority:   2,
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "B",
+					Priority:   2,
+				},
+			},
+		},
+		{
+			testID: "Negative priority loses to unprioritized period",
+			ts:     now,
+			result: "A",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Hour),
+					EndTime:    now.Add(time.Hour),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "B",
+					Priority:   -1,
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			id, err := MostSpecificPeriod(tc.ts, tc.periods...)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if id != tc.result {
+				t.Errorf("ID '%s' does not match expected '%s'", id, tc.result)
+			}
+		})
+	}
+}
+
+func TestMostSpecificPeriodOpenEnded(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		ts      time.Time
+		testID  string
+		result  string
+		periods []Period
+	}{
+		{
+			testID: "Bounded beats open-ended",
+			ts:     now,
+			result: "B",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-100 * 365 * 24 * time.Hour),
+					EndTime:    now.Add(100 * 365 * 24 * time.Hour),
+					Identifier: "B",
+				},
+			},
+		},
+		{
+			testID: "Open on one side beats open on both",
+			ts:     now,
+			result: "B",
+			periods: []Period{
+				TimeWindow{
+					Identifier: "A",
+				},
+				TimeWindow{
+					EndTime:    now.Add(time.Minute),
+					Identifier: "B",
+				},
+			},
+		},
+		{
+			testID: "Two open-ended periods, later start wins",
+			ts:     now,
+			result: "A",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Hour),
+					Identifier: "B",
+				},
+			},
+		},
+		{
+			testID: "Started and ending periods, started wins",
+			ts:     now,
+			result: "A",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					EndTime:    now.Add(time.Minute),
+					Identifier: "B",
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			id, err := MostSpecificPeriod(tc.ts, tc.periods...)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if id != tc.result {
+				t.Errorf("ID '%s' does not match expected '%s'", id, tc.result)
+			}
+			id, err = NewResolver(tc.periods...).Resolve(tc.ts)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if id != tc.result {
+				t.Errorf("Resolver ID '%s' does not match expected '%s'", id, tc.result)
+			}
+		})
+	}
+}
+
+func TestRankedPeriods(t *testing.T) {
+	// use a static timestamp to make sure tests don't fail on slower systems or during a process pause
+	now := time.Now()
+	testCases := []struct {
+		testID  string
+		periods []Period
+		result  []string
+	}{
+		{
+			testID:  "No choices",
+			periods: []Period{},
+			result:  nil,
+		},
+		{
+			testID: "Fallback chain",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Hour),
+					EndTime:    now.Add(time.Hour),
+					Identifier: "season",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "flash sale",
+				},
+				TimeWindow{
+					StartTime:  now.Add(time.Minute),
+					EndTime:    now.Add(2 * time.Minute),
+					Identifier: "later",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-2 * time.Hour),
+					Identifier: "default",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Hour),
+					Identifier: "week",
+				},
+			},
+			result: []string{"flash sale", "week", "season", "default"},
+		},
+		{
+			testID: "Priority first",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Hour),
+					EndTime:    now.Add(time.Hour),
+					Identifier: "B",
+					Priority:   1,
+				},
+			},
+			result: []string{"B", "A"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			var ids []string
+			for _, p := range RankedPeriods(now, tc.periods...) {
+				ids = append(ids, p.GetIdentifier())
+			}
+			if !slicesEqual(ids, tc.result) {
+				t.Errorf("Expected %v but got %v", tc.result, ids)
+			}
+			if len(ids) > 0 {
+				id, _ := MostSpecificPeriod(now, tc.periods...)
+				if id != ids[0] {
+					t.Errorf("First ranked '%s' does not match MostSpecificPeriod '%s'", ids[0], id)
+				}
+			}
+			for n := -1; n <= len(tc.result)+1; n++ {
+				top := TopN(now, n, tc.periods...)
+				expected := min(max(n, 0), len(tc.result))
+				if len(top) != expected {
+					t.Errorf("TopN(%d) returned %d periods, expected %d", n, len(top), expected)
+				}
+				for i, p := range top {
+					if p.GetIdentifier() != tc.result[i] {
+						t.Errorf("TopN(%d) entry %d '%s' does not match expected '%s'", n, i, p.GetIdentifier(), tc.result[i])
+					}
+				}
+			}
+		})
+	}
+}
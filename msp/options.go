@@ -0,0 +1,27 @@
+package msp
+
+import "time"
+
+// Options adjusts how MostSpecificPeriodWithOptions picks a period. The zero
+// value behaves like MostSpecificPeriod.
+type Options struct {
+	// Policy ranks the periods containing the timestamp. The zero Policy is
+	// DefaultPolicy.
+	Policy Policy
+	// Strict rejects the whole input if any period fails Validate, instead
+	// of skipping inverted and zero-length periods silently.
+	Strict bool
+}
+
+// MostSpecificPeriodWithOptions returns the identifier of the most specific
+// period containing ts as configured by opts. In strict mode the error
+// returned by Validate is passed through, so errors.As finds the offending
+// *ValidationError.
+func MostSpecificPeriodWithOptions(ts time.Time, opts Options, periods ...Period) (id string, err error) {
+	if opts.Strict {
+		if err := Validate(periods...); err != nil {
+			return "", err
+		}
+	}
+	return opts.Policy.MostSpecificPeriod(ts, periods...)
+}
@@ -0,0 +1,90 @@
+package msp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMostSpecificPeriodWithOptions(t *testing.T) {
+	// use a static timestamp to make sure tests don't fail on slower systems or during a process pause
+	now := time.Now()
+	valid := []Period{
+		TimeWindow{
+			StartTime:  now.Add(-time.Hour),
+			EndTime:    now.Add(time.Hour),
+			Identifier: "A",
+		},
+		TimeWindow{
+			StartTime:  now.Add(-time.Minute),
+			EndTime:    now.Add(time.Hour),
+			Identifier: "B",
+		},
+	}
+	inverted := append([]Period{
+		TimeWindow{
+			StartTime:  now.Add(time.Minute),
+			EndTime:    now.Add(-time.Minute),
+			Identifier: "inverted",
+		},
+	}, valid...)
+	testCases := []struct {
+		testID  string
+		opts    Options
+		periods []Period
+		result  string
+		err     error
+	}{
+		{
+			testID:  "Lenient skips inverted periods",
+			opts:    Options{},
+			periods: inverted,
+			result:  "B",
+			err:     nil,
+		},
+		{
+			testID:  "Strict accepts valid periods",
+			opts:    Options{Strict: true},
+			periods: valid,
+			result:  "B",
+			err:     nil,
+		},
+		{
+			testID:  "Strict rejects inverted periods",
+			opts:    Options{Strict: true},
+			periods: inverted,
+			result:  "",
+			err:     ErrEndAfterStart,
+		},
+		{
+			testID:  "Strict with no periods",
+			opts:    Options{Strict: true},
+			periods: []Period{},
+			result:  "",
+			err:     ErrNoValidPeriods,
+		},
+		{
+			testID:  "Custom policy",
+			opts:    Options{Policy: NewPolicy(LongestDuration)},
+			periods: valid,
+			result:  "A",
+			err:     nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			id, err := MostSpecificPeriodWithOptions(now, tc.opts, tc.periods...)
+			if id != tc.result {
+				t.Errorf("Result '%s' does not match expected '%s'", id, tc.result)
+			}
+			if !errors.Is(err, tc.err) || (err == nil) != (tc.err == nil) {
+				t.Errorf("Error '%v' does not match expected '%v'", err, tc.err)
+			}
+		})
+	}
+	_, err := MostSpecificPeriodWithOptions(now, Options{Strict: true}, inverted...)
+	var v *ValidationError
+	if !errors.As(err, &v) || v.Index != 0 || v.Identifier != "inverted" {
+		t.Errorf("Expected a ValidationError for period 0 but got '%v'", err)
+	}
+}
@@ -0,0 +1,131 @@
+package msp
+
+import (
+	"cmp"
+	"strings"
+	"time"
+)
+
+// Comparator compares two periods that both contain the queried timestamp.
+// It returns a negative number when a is more specific than b, a positive
+// number when b is more specific than a, and zero when it cannot tell them
+// apart.
+type Comparator func(a, b Period) int
+
+// Rule is a named Comparator, used as one criterion of a Policy.
+type Rule struct {
+	Name    string
+	Compare Comparator
+}
+
+var (
+	// HighestPriority prefers the period with the higher priority, as
+	// reported by GetPriority.
+	HighestPriority = Rule{
+		Name: "highest priority",
+		Compare: func(a, b Period) int {
+			return cmp.Compare(GetPriority(b), GetPriority(a))
+		},
+	}
+	// ShortestDuration prefers the period with the shorter duration. A
+	// bounded period is always shorter than one missing a start or end
+	// time, which in turn is shorter than one missing both. Periods with a
+	// Granularity last the nominal length of their calendar unit.
+	ShortestDuration = Rule{
+		Name: "shortest duration",
+		Compare: func(a, b Period) int {
+			if c := cmp.Compare(openEnds(a), openEnds(b)); c != 0 {
+				return c
+			}
+			return cmp.Compare(specificity(a), specificity(b))
+		},
+	}
+	// LongestDuration prefers the period with the longer duration.
+	LongestDuration = Rule{
+		Name: "longest duration",
+		Compare: func(a, b Period) int {
+			return ShortestDuration.Compare(b, a)
+		},
+	}
+	// LatestStart prefers the period that started most recently.
+	LatestStart = Rule{
+		Name: "latest start",
+		Compare: func(a, b Period) int {
+			return startOf(b).Compare(startOf(a))
+		},
+	}
+	// EarliestStart prefers the period that started first.
+	EarliestStart = Rule{
+		Name: "earliest start",
+		Compare: func(a, b Period) int {
+			return startOf(a).Compare(startOf(b))
+		},
+	}
+	// LastIdentifier prefers the lexicographically last identifier.
+	LastIdentifier = Rule{
+		Name: "last identifier",
+		Compare: func(a, b Period) int {
+			return strings.Compare(b.GetIdentifier(), a.GetIdentifier())
+		},
+	}
+	// FirstIdentifier prefers the lexicographically first identifier.
+	FirstIdentifier = Rule{
+		Name: "first identifier",
+		Compare: func(a, b Period) int {
+			return strings.Compare(a.GetIdentifier(), b.GetIdentifier())
+		},
+	}
+)
+
+// Policy decides which of several periods containing a timestamp is the most
+// specific. Rules are applied in order and the first rule that tells two
+// periods apart decides between them. Periods that no rule can tell apart
+// are ranked by their position in the input, earliest first.
+//
+// The zero value is equivalent to DefaultPolicy.
+type Policy struct {
+	Rules []Rule
+}
+
+// DefaultPolicy is the ranking used by the package-level functions: the
+// highest priority wins, then the shortest duration, then the latest start
+// time, then the lexicographically last identifier.
+var DefaultPolicy = Policy{
+	Rules: []Rule{HighestPriority, ShortestDuration, LatestStart, LastIdentifier},
+}
+
+// NewPolicy returns a Policy applying rules in the given order. Unlike the
+// zero Policy, a Policy built from no rules ranks periods by input order.
+func NewPolicy(rules ...Rule) Policy {
+	return Policy{Rules: append([]Rule{}, rules...)}
+}
+
+// Compare applies the policy's rules to a and b. It returns a negative
+// number when a is more specific than b, a positive number when b is more
+// specific than a, and zero when no rule tells them apart.
+func (p Policy) Compare(a, b Period) int {
+	for _, r := range p.rules() {
+		if c := r.Compare(a, b); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// rules returns the policy's rules, falling back to those of DefaultPolicy
+// for the zero Policy.
+func (p Policy) rules() []Rule {
+	if p.Rules == nil {
+		return DefaultPolicy.Rules
+	}
+	return p.Rules
+}
+
+// specificity returns the duration by which ShortestDuration ranks p.
+func specificity(p Period) time.Duration {
+	if g := GetGranularity(p); g != NoGranularity {
+		return g.Nominal()
+	}
+	d, _ := GetDuration(startOf(p), endOf(p))
+	return d
+}
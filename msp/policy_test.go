@@ -0,0 +1,135 @@
+package msp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPolicyMostSpecificPeriod(t *testing.T) {
+	// use a static timestamp to make sure tests don't fail on slower systems or during a process pause
+	now := time.Now()
+	periods := []Period{
+		TimeWindow{
+			StartTime:  now.Add(-10 * time.Minute),
+			EndTime:    now.Add(10 * time.Minute),
+			Identifier: "A",
+		},
+		TimeWindow{
+			StartTime:  now.Add(-5 * time.Minute),
+			EndTime:    now.Add(5 * time.Minute),
+			Identifier: "B",
+		},
+		TimeWindow{
+			StartTime:  now.Add(-3 * time.Minute),
+			EndTime:    now.Add(7 * time.Minute),
+			Identifier: "C",
+		},
+	}
+	testCases := []struct {
+		testID string
+		policy Policy
+		result string
+	}{
+		{
+			testID: "Zero value is the default",
+			policy: Policy{},
+			result: "C",
+		},
+		{
+			testID: "Default policy",
+			policy: DefaultPolicy,
+			result: "C",
+		},
+		{
+			testID: "Earliest start wins",
+			policy: NewPolicy(EarliestStart),
+			result: "A",
+		},
+		{
+			testID: "Shortest duration, then earliest start",
+			policy: NewPolicy(ShortestDuration, EarliestStart),
+			result: "B",
+		},
+		{
+			testID: "Longest duration",
+			policy: NewPolicy(LongestDuration),
+			result: "A",
+		},
+		{
+			testID: "First identifier",
+			policy: NewPolicy(FirstIdentifier),
+			result: "A",
+		},
+		{
+			testID: "No rules distinguish, first input wins",
+			policy: NewPolicy(),
+			result: "A",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			id, err := tc.policy.MostSpecificPeriod(now, periods...)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if id != tc.result {
+				t.Errorf("ID '%s' does not match expected '%s'", id, tc.result)
+			}
+			id, err = tc.policy.NewResolver(periods...).Resolve(now)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if id != tc.result {
+				t.Errorf("Resolver ID '%s' does not match expected '%s'", id, tc.result)
+			}
+		})
+	}
+}
+
+func TestPolicyTimeline(t *testing.T) {
+	now := time.Now()
+	policy := NewPolicy(EarliestStart, LastIdentifier)
+	periods := []Period{
+		TimeWindow{
+			StartTime:  now.Add(-10 * time.Minute),
+			EndTime:    now,
+			Identifier: "A",
+		},
+		TimeWindow{
+			StartTime:  now.Add(-5 * time.Minute),
+			EndTime:    now.Add(5 * time.Minute),
+			Identifier: "B",
+		},
+	}
+
+	changeovers := policy.GetChangeOvers(periods...)
+	expectedChangeovers := []time.Time{now.Add(-10 * time.Minute), now, now.Add(5 * time.Minute)}
+	if !slicesEqual(changeovers, expectedChangeovers) {
+		t.Errorf("Expected changeovers %v but got %v", expectedChangeovers, changeovers)
+	}
+
+	ids := policy.FlattenPeriods(periods...)
+	if !slicesEqual(ids, []string{"A", "B"}) {
+		t.Errorf("Expected flattened periods [A B] but got %v", ids)
+	}
+
+	next, err := policy.GetNextChangeOver(now.Add(-time.Minute), periods...)
+	if err != nil || !next.Equal(now) {
+		t.Errorf("Expected next changeover %v but got %v (%v)", now, next, err)
+	}
+
+	expectedTimeline := []string{
+		fmt.Sprintf("A\t%s\t%s", now.Add(-10*time.Minute), now),
+		fmt.Sprintf("B\t%s\t%s", now, now.Add(5*time.Minute)),
+	}
+	timeline := policy.GenerateTimeline(periods...)
+	if len(timeline) != len(expectedTimeline) {
+		t.Fatalf("Time line had %d results, expected %d", len(timeline), len(expectedTimeline))
+	}
+	for idx, period := range timeline {
+		if period.(TimeWindow).String() != expectedTimeline[idx] {
+			t.Errorf("Expected:\t%s\nHad:\t%s", expectedTimeline[idx], period)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+package msp
+
+import "time"
+
+// Frequency is the calendar unit at which a Recurrence repeats.
+type Frequency int
+
+const (
+	// Once means the period does not repeat.
+	Once Frequency = iota
+	Daily
+	Weekly
+	Monthly
+	Yearly
+)
+
+// maxEmptyPeriods bounds how many consecutive frequency periods may yield no
+// occurrence before a recurrence is considered exhausted, so that rules
+// which can never match do not loop forever.
+const maxEmptyPeriods = 4000
+
+// WeekdayNum selects a weekday, optionally restricted to its Nth occurrence
+// within the month. N of 0 matches every such weekday, and a negative N
+// counts from the end of the month, so -1 is the last. N is only used by
+// Monthly and Yearly recurrences.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// Recurrence describes how a RecurringPeriod repeats. It follows the
+// RRULE semantics of RFC 5545 for the subset of rule parts it supports.
+type Recurrence struct {
+	// Frequency is the unit of repetition.
+	Frequency Frequency
+	// Interval is the number of Frequency units between repetitions. Zero
+	// is treated as 1.
+	Interval int
+	// ByDay limits Daily and expands Weekly recurrences to the listed
+	// weekdays. For Monthly recurrences it selects weekdays within each
+	// month, and for Yearly recurrences weekdays within the month of the
+	// first occurrence.
+	ByDay []WeekdayNum
+	// ByMonthDay selects days of the month. Negative values count from the
+	// end of the month, so -1 is the last day.
+	ByMonthDay []int
+	// Count limits the total number of occurrences, including excluded
+	// ones. Zero means no limit.
+	Count int
+	// Until is the last instant at which an occurrence may start. The zero
+	// value means no limit.
+	Until time.Time
+	// Exclude lists occurrence start times to skip.
+	Exclude []time.Time
+}
+
+// Recurring is implemented by periods that stand for a series of
+// occurrences. Functions in this package expand a Recurring period into its
+// individual occurrences and rank each occurrence on its own. The
+// GetStartTime and GetEndTime of a Recurring period span all of its
+// occurrences; GetEndTime returns the zero time for a series without end.
+type Recurring interface {
+	Period
+	// Occurrences returns the occurrences overlapping [from, to) in
+	// chronological order.
+	Occurrences(from, to time.Time) []Period
+}
+
+// Compile-time interface check.
+var _ Recurring = RecurringPeriod{}
+
+// RecurringPeriod is a period that repeats according to a Recurrence. Each
+// occurrence starts at the wall-clock time of StartTime in its location and
+// lasts for Duration. Bounds applies to every occurrence.
+type RecurringPeriod struct {
+	StartTime  time.Time
+	Duration   time.Duration
+	Identifier string
+	Priority   int
+	Bounds     Bounds
+	Recurrence Recurrence
+}
+
+// GetIdentifier returns the period's identifier string.
+func (p RecurringPeriod) GetIdentifier() string {
+	return p.Identifier
+}
+
+// GetStartTime returns the start time of the first occurrence.
+func (p RecurringPeriod) GetStartTime() time.Time {
+	return p.StartTime
+}
+
+// GetEndTime returns the exclusive end time of the last occurrence, or the
+// zero time if the recurrence has neither a Count nor an Until.
+func (p RecurringPeriod) GetEndTime() time.Time {
+	if p.Recurrence.Frequency != Once && p.Recurrence.Count == 0 && p.Recurrence.Until.IsZero() {
+		return time.Time{}
+	}
+	var last time.Time
+	p.eachStart(p.StartTime, func(start time.Time) bool {
+		last = start
+		return true
+	})
+	if last.IsZero() {
+		return p.StartTime
+	}
+	return last.Add(p.Duration)
+}
+
+// GetPriority returns the period's explicit priority.
+func (p RecurringPeriod) GetPriority() int {
+	return p.Priority
+}
+
+// GetBounds returns whether occurrences contain their start and end times.
+func (p RecurringPeriod) GetBounds() Bounds {
+	return p.Bounds
+}
+
+// Occurrences returns the occurrences of p overlapping [from, to) as
+// TimeWindow values sharing p's identifier, priority and bounds.
+func (p RecurringPeriod) Occurrences(from, to time.Time) []Period {
+	if !from.Before(to) {
+		return nil
+	}
+	var out []Period
+	p.eachStart(from.Add(-p.Duration-time.Nanosecond), func(start time.Time) bool {
+		if !start.Before(to) {
+			return false
+		}
+		o := TimeWindow{
+			StartTime:  start,
+			EndTime:    start.Add(p.Duration),
+			Identifier: p.Identifier,
+			Priority:   p.Priority,
+			Bounds:     p.Bounds,
+		}
+		if overlaps(o, from, to) {
+			out = append(out, o)
+		}
+		return true
+	})
+	return out
+}
+
+// eachStart calls fn with the start of each occurrence in chronological
+// order until fn returns false or the recurrence is exhausted. Occurrences
+// starting before hint may be skipped.
+func (p RecurringPeriod) eachStart(hint time.Time, fn func(time.Time) bool) {
+	r := p.Recurrence
+	if r.Frequency == Once {
+		if !p.excluded(p.StartTime) {
+			fn(p.StartTime)
+		}
+		return
+	}
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+	count := 0
+	empty := 0
+	// Without a Count, occurrences before hint need not be generated, so
+	// start one interval short of the period containing it.
+	k := 0
+	if r.Count == 0 && hint.After(p.StartTime) {
+		k = max(p.periodsBetween(p.StartTime, hint)/interval-1, 0)
+	}
+	for ; empty < maxEmptyPeriods; k++ {
+		candidates := p.candidates(k * interval)
+		if len(candidates) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		for _, start := range candidates {
+			if start.Before(p.StartTime) {
+				continue
+			}
+			if !r.Until.IsZero() && start.After(r.Until) {
+				return
+			}
+			count++
+			if !p.excluded(start) && !fn(start) {
+				return
+			}
+			if r.Count > 0 && count >= r.Count {
+				return
+			}
+		}
+	}
+}
+
+// periodsBetween returns the number of whole frequency units from a to b.
+func (p RecurringPeriod) periodsBetween(a, b time.Time) int {
+	switch p.Recurrence.Frequency {
+	case Daily:
+		return int(b.Sub(a) / (24 * time.Hour))
+	case Weekly:
+		return int(b.Sub(a) / (7 * 24 * time.Hour))
+	case Monthly:
+		return (b.Year()-a.Year())*12 + int(b.Month()) - int(a.Month()) - 1
+	case Yearly:
+		return b.Year() - a.Year() - 1
+	}
+	return 0
+}
+
+// candidates returns the occurrence starts, in order, within the frequency
+// period offset units after the one containing StartTime.
+func (p RecurringPeriod) candidates(offset int) []time.Time {
+	r := p.Recurrence
+	first := p.StartTime
+	y, m, d := first.Date()
+	var days []time.Time
+	switch r.Frequency {
+	case Daily:
+		day := date(y, m, d+offset, first.Location())
+		if p.matchesWeekday(day) && p.matchesMonthDay(day) {
+			days = append(days, day)
+		}
+	case Weekly:
+		// weeks start on Monday, as with the RFC 5545 default WKST
+		monday := date(y, m, d-(int(first.Weekday())+6)%7+7*offset, first.Location())
+		weekdays := []time.Weekday{first.Weekday()}
+		if len(r.ByDay) > 0 {
+			weekdays = weekdays[:0]
+			for _, wd := range r.ByDay {
+				weekdays = append(weekdays, wd.Weekday)
+			}
+		}
+		for i := 0; i < 7; i++ {
+			day := monday.AddDate(0, 0, i)
+			for _, wd := range weekdays {
+				if day.Weekday() == wd && p.matchesMonthDay(day) {
+					days = append(days, day)
+					break
+				}
+			}
+		}
+	case Monthly:
+		days = p.monthDays(y, m+time.Month(offset))
+	case Yearly:
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			// an anniversary that does not exist this year, such as
+			// February 29th, is skipped
+			day := date(y+offset, m, d, first.Location())
+			if day.Day() == d {
+				days = append(days, day)
+			}
+		} else {
+			days = p.monthDays(y+offset, m)
+		}
+	}
+	out := make([]time.Time, 0, len(days))
+	hour, minute, sec := first.Clock()
+	for _, day := range days {
+		dy, dm, dd := day.Date()
+		out = append(out, time.Date(dy, dm, dd, hour, minute, sec, first.Nanosecond(), first.Location()))
+	}
+	return out
+}
+
+// monthDays returns the days of the given month selected by ByDay and
+// ByMonthDay, defaulting to the day of the month of StartTime.
+func (p RecurringPeriod) monthDays(year int, month time.Month) []time.Time {
+	r := p.Recurrence
+	loc := p.StartTime.Location()
+	firstDay := date(year, month, 1, loc)
+	year, month, _ = firstDay.Date()
+	n := daysIn(year, month, loc)
+	var days []time.Time
+	for d := 1; d <= n; d++ {
+		day := date(year, month, d, loc)
+		switch {
+		case len(r.ByDay) == 0 && len(r.ByMonthDay) == 0:
+			if d == p.StartTime.Day() {
+				days = append(days, day)
+			}
+		case p.matchesMonthDay(day) && p.matchesNthWeekday(day, n):
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// matchesWeekday reports whether day's weekday is listed in ByDay, ignoring
+// ordinals. An empty ByDay matches every day.
+func (p RecurringPeriod) matchesWeekday(day time.Time) bool {
+	if len(p.Recurrence.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range p.Recurrence.ByDay {
+		if wd.Weekday == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNthWeekday reports whether day matches an entry of ByDay,
+// including its ordinal within a month of n days. An empty ByDay matches
+// every day.
+func (p RecurringPeriod) matchesNthWeekday(day time.Time, n int) bool {
+	if len(p.Recurrence.ByDay) == 0 {
+		return true
+	}
+	fromStart := (day.Day()-1)/7 + 1
+	fromEnd := -((n-day.Day())/7 + 1)
+	for _, wd := range p.Recurrence.ByDay {
+		if wd.Weekday != day.Weekday() {
+			continue
+		}
+		if wd.N == 0 || wd.N == fromStart || wd.N == fromEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMonthDay reports whether day is listed in ByMonthDay. An empty
+// ByMonthDay matches every day.
+func (p RecurringPeriod) matchesMonthDay(day time.Time) bool {
+	if len(p.Recurrence.ByMonthDay) == 0 {
+		return true
+	}
+	n := daysIn(day.Year(), day.Month(), day.Location())
+	for _, md := range p.Recurrence.ByMonthDay {
+		if md == day.Day() || md < 0 && n+1+md == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether start is listed in Exclude.
+func (p RecurringPeriod) excluded(start time.Time) bool {
+	for _, x := range p.Recurrence.Exclude {
+		if x.Equal(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// date returns noon on the given (normalized) day, which is safe from
+// daylight saving transitions when doing day arithmetic.
+func date(year int, month time.Month, day int, loc *time.Location) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, loc)
+}
+
+// daysIn returns the number of days in the given month.
+func daysIn(year int, month time.Month, loc *time.Location) int {
+	return date(year, month+1, 0, loc).Day()
+}
+
+// Expand replaces every Recurring period with its occurrences overlapping
+// [from, to) and returns the other periods unchanged. Use it to choose how
+// far recurrences without a Count or Until are expanded.
+func Expand(from, to time.Time, periods ...Period) []Period {
+	var out []Period
+	for _, p := range periods {
+		if r, ok := p.(Recurring); ok {
+			out = append(out, r.Occurrences(from, to)...)
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// instance is a concrete period, either an input period or one occurrence of
+// a Recurring input period, together with the position of that input.
+type instance struct {
+	Period
+	index int
+}
+
+// GetStartTime returns the first instant contained in the instance, taking
+// its Bounds into account, so that the sweep can treat every instance as
+// [start, end).
+func (x instance) GetStartTime() time.Time {
+	return startOf(x.Period)
+}
+
+// GetEndTime returns the first instant after the instance, taking its Bounds
+// into account.
+func (x instance) GetEndTime() time.Time {
+	return endOf(x.Period)
+}
+
+// identifier returns the instance's identifier, or the empty string for the
+// zero instance.
+func (x instance) identifier() string {
+	if x.Period == nil {
+		return ""
+	}
+	return x.GetIdentifier()
+}
+
+// expandAt returns the instances of periods that may contain ts: every
+// occurrence of a Recurring period overlapping ts, and every other period.
+func expandAt(ts time.Time, periods []Period) []instance {
+	return expandBetween(ts, ts.Add(time.Nanosecond), periods)
+}
+
+// expandBetween returns the instances of periods that may overlap
+// [from, to): every occurrence of a Recurring period overlapping the range,
+// and every other period.
+func expandBetween(from, to time.Time, periods []Period) []instance {
+	var out []instance
+	for i, p := range periods {
+		r, ok := p.(Recurring)
+		if !ok {
+			out = append(out, instance{Period: p, index: i})
+			continue
+		}
+		for _, o := range r.Occurrences(from, to) {
+			out = append(out, instance{Period: o, index: i})
+		}
+	}
+	return out
+}
+
+// expandAll returns the instances of periods, replacing every Recurring
+// period with its occurrences. Series without end are expanded up to the
+// horizon returned by horizonOf, which is zero if there are none.
+func expandAll(periods []Period) (out []instance, horizon time.Time) {
+	horizon = horizonOf(periods)
+	for i, p := range periods {
+		r, ok := p.(Recurring)
+		if !ok {
+			out = append(out, instance{Period: p, index: i})
+			continue
+		}
+		end := r.GetEndTime()
+		if end.IsZero() {
+			// include occurrences starting at the horizon, so that the
+			// winner there is known
+			end = horizon.Add(time.Nanosecond)
+		}
+		for _, o := range r.Occurrences(r.GetStartTime(), end) {
+			out = append(out, instance{Period: o, index: i})
+		}
+	}
+	return out, horizon
+}
+
+// horizonOf returns how far functions without a range of their own expand
+// recurring periods without end: to the latest start or end time of the
+// other periods and the bounded series, and at least to the end of the first
+// occurrence of every endless series. It returns the zero time if there is
+// no endless series.
+func horizonOf(periods []Period) (horizon time.Time) {
+	endless := false
+	later := func(t time.Time) {
+		if t.After(horizon) {
+			horizon = t
+		}
+	}
+	for _, p := range periods {
+		r, ok := p.(Recurring)
+		if !ok {
+			later(p.GetStartTime())
+			later(p.GetEndTime())
+			continue
+		}
+		later(r.GetStartTime())
+		if end := r.GetEndTime(); !end.IsZero() {
+			later(end)
+			continue
+		}
+		endless = true
+		for _, o := range r.Occurrences(r.GetStartTime(), r.GetStartTime().Add(time.Nanosecond)) {
+			later(o.GetEndTime())
+		}
+	}
+	if !endless {
+		return time.Time{}
+	}
+	return horizon
+}
@@ -0,0 +1,313 @@
+package msp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurringPeriodOccurrences(t *testing.T) {
+	// Monday, June 3rd 2024
+	monday := time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC)
+	weekdays := []WeekdayNum{{Weekday: time.Monday}, {Weekday: time.Tuesday}, {Weekday: time.Wednesday}, {Weekday: time.Thursday}, {Weekday: time.Friday}}
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2024, month, d, 9, 0, 0, 0, time.UTC)
+	}
+	testCases := []struct {
+		testID     string
+		recurrence Recurrence
+		from       time.Time
+		to         time.Time
+		result     []time.Time
+	}{
+		{
+			testID:     "Once",
+			recurrence: Recurrence{},
+			from:       day(time.June, 1),
+			to:         day(time.July, 1),
+			result:     []time.Time{day(time.June, 3)},
+		},
+		{
+			testID:     "Every weekday",
+			recurrence: Recurrence{Frequency: Weekly, ByDay: weekdays},
+			from:       day(time.June, 6),
+			to:         day(time.June, 12),
+			result:     []time.Time{day(time.June, 6), day(time.June, 7), day(time.June, 10), day(time.June, 11)},
+		},
+		{
+			testID:     "Daily limited to weekdays",
+			recurrence: Recurrence{Frequency: Daily, ByDay: weekdays},
+			from:       day(time.June, 6),
+			to:         day(time.June, 12),
+			result:     []time.Time{day(time.June, 6), day(time.June, 7), day(time.June, 10), day(time.June, 11)},
+		},
+		{
+			testID:     "Every other day",
+			recurrence: Recurrence{Frequency: Daily, Interval: 2},
+			from:       day(time.June, 1),
+			to:         day(time.June, 10),
+			result:     []time.Time{day(time.June, 3), day(time.June, 5), day(time.June, 7), day(time.June, 9)},
+		},
+		{
+			testID:     "First Monday of each month",
+			recurrence: Recurrence{Frequency: Monthly, ByDay: []WeekdayNum{{Weekday: time.Monday, N: 1}}},
+			from:       day(time.June, 1),
+			to:         day(time.September, 30),
+			result:     []time.Time{day(time.June, 3), day(time.July, 1), day(time.August, 5), day(time.September, 2)},
+		},
+		{
+			testID:     "Last Friday of each month",
+			recurrence: Recurrence{Frequency: Monthly, ByDay: []WeekdayNum{{Weekday: time.Friday, N: -1}}},
+			from:       day(time.June, 1),
+			to:         day(time.August, 31),
+			result:     []time.Time{day(time.June, 28), day(time.July, 26), day(time.August, 30)},
+		},
+		{
+			testID:     "Last day of each month",
+			recurrence: Recurrence{Frequency: Monthly, ByMonthDay: []int{-1}},
+			from:       day(time.June, 1),
+			to:         day(time.September, 1),
+			result:     []time.Time{day(time.June, 30), day(time.July, 31), day(time.August, 31)},
+		},
+		{
+			testID:     "Count limits occurrences",
+			recurrence: Recurrence{Frequency: Daily, Count: 3},
+			from:       day(time.June, 1),
+			to:         day(time.July, 1),
+			result:     []time.Time{day(time.June, 3), day(time.June, 4), day(time.June, 5)},
+		},
+		{
+			testID:     "Until is inclusive",
+			recurrence: Recurrence{Frequency: Daily, Until: day(time.June, 5)},
+			from:       day(time.June, 1),
+			to:         day(time.July, 1),
+			result:     []time.Time{day(time.June, 3), day(time.June, 4), day(time.June, 5)},
+		},
+		{
+			testID:     "Excluded occurrences still count",
+			recurrence: Recurrence{Frequency: Daily, Count: 3, Exclude: []time.Time{day(time.June, 4)}},
+			from:       day(time.June, 1),
+			to:         day(time.July, 1),
+			result:     []time.Time{day(time.June, 3), day(time.June, 5)},
+		},
+		{
+			testID:     "Yearly anniversary",
+			recurrence: Recurrence{Frequency: Yearly},
+			from:       day(time.January, 1),
+			to:         time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+			result:     []time.Time{day(time.June, 3), time.Date(2025, time.June, 3, 9, 0, 0, 0, time.UTC), time.Date(2026, time.June, 3, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			testID:     "Far from the first occurrence",
+			recurrence: Recurrence{Frequency: Weekly},
+			from:       time.Date(2034, time.June, 1, 0, 0, 0, 0, time.UTC),
+			to:         time.Date(2034, time.June, 10, 0, 0, 0, 0, time.UTC),
+			result:     []time.Time{time.Date(2034, time.June, 5, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			testID:     "Occurrence overlapping the start of the range",
+			recurrence: Recurrence{Frequency: Daily},
+			from:       day(time.June, 4).Add(2 * time.Hour),
+			to:         day(time.June, 5),
+			result:     []time.Time{day(time.June, 4)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			p := RecurringPeriod{
+				StartTime:  monday,
+				Duration:   8 * time.Hour,
+				Identifier: "office",
+				Recurrence: tc.recurrence,
+			}
+			var starts []time.Time
+			for _, o := range p.Occurrences(tc.from, tc.to) {
+				starts = append(starts, o.GetStartTime())
+				if o.GetEndTime().Sub(o.GetStartTime()) != 8*time.Hour {
+					t.Errorf("Occurrence %v does not last 8h", o)
+				}
+			}
+			if !slicesEqual(starts, tc.result) {
+				t.Errorf("Expected %v but got %v", tc.result, starts)
+			}
+		})
+	}
+}
+
+func TestRecurringPeriodWallClock(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	p := RecurringPeriod{
+		StartTime:  time.Date(2024, time.March, 8, 9, 0, 0, 0, loc),
+		Duration:   8 * time.Hour,
+		Identifier: "office",
+		Recurrence: Recurrence{Frequency: Daily, Count: 4},
+	}
+	for _, o := range p.Occurrences(p.GetStartTime(), p.GetEndTime()) {
+		if h := o.GetStartTime().In(loc).Hour(); h != 9 {
+			t.Errorf("Occurrence %v starts at %d:00 local time, expected 9:00", o, h)
+		}
+	}
+}
+
+func TestRecurringPeriodGetEndTime(t *testing.T) {
+	start := time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC)
+	p := RecurringPeriod{
+		StartTime:  start,
+		Duration:   time.Hour,
+		Identifier: "standup",
+		Recurrence: Recurrence{Frequency: Weekly, Count: 3},
+	}
+	if end := p.GetEndTime(); !end.Equal(start.AddDate(0, 0, 14).Add(time.Hour)) {
+		t.Errorf("Unexpected end time %v", end)
+	}
+	p.Recurrence.Count = 0
+	if end := p.GetEndTime(); !end.IsZero() {
+		t.Errorf("Expected zero end time for an endless series, got %v", end)
+	}
+}
+
+func TestMostSpecificPeriodRecurring(t *testing.T) {
+	monday := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+	periods := []Period{
+		TimeWindow{
+			StartTime:  monday,
+			EndTime:    monday.AddDate(0, 1, 0),
+			Identifier: "june",
+		},
+		RecurringPeriod{
+			StartTime:  monday.Add(9 * time.Hour),
+			Duration:   8 * time.Hour,
+			Identifier: "business-hours",
+			Recurrence: Recurrence{
+				Frequency: Weekly,
+				ByDay:     []WeekdayNum{{Weekday: time.Monday}, {Weekday: time.Tuesday}, {Weekday: time.Wednesday}, {Weekday: time.Thursday}, {Weekday: time.Friday}},
+			},
+		},
+	}
+	testCases := []struct {
+		testID string
+		ts     time.Time
+		result string
+	}{
+		{
+			testID: "During business hours",
+			ts:     monday.AddDate(0, 0, 2).Add(12 * time.Hour),
+			result: "business-hours",
+		},
+		{
+			testID: "Evening",
+			ts:     monday.AddDate(0, 0, 2).Add(20 * time.Hour),
+			result: "june",
+		},
+		{
+			testID: "Weekend",
+			ts:     monday.AddDate(0, 0, 5).Add(12 * time.Hour),
+			result: "june",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			id, err := MostSpecificPeriod(tc.ts, periods...)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if id != tc.result {
+				t.Errorf("ID '%s' does not match expected '%s'", id, tc.result)
+			}
+		})
+	}
+}
+
+func TestGetChangeOversRecurring(t *testing.T) {
+	start := time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC)
+	bounded := RecurringPeriod{
+		StartTime:  start,
+		Duration:   time.Hour,
+		Identifier: "standup",
+		Recurrence: Recurrence{Frequency: Daily, Count: 2},
+	}
+	expected := []time.Time{start, start.Add(time.Hour), start.AddDate(0, 0, 1), start.AddDate(0, 0, 1).Add(time.Hour)}
+	if changeovers := GetChangeOvers(bounded); !slicesEqual(changeovers, expected) {
+		t.Errorf("Expected %v but got %v", expected, changeovers)
+	}
+
+	endless := bounded
+	endless.Recurrence.Count = 0
+	// on its own, an unbounded series is expanded through its first occurrence
+	if changeovers := GetChangeOvers(endless); !slicesEqual(changeovers, expected[:2]) {
+		t.Errorf("Expected %v for an unbounded series, got %v", expected[:2], changeovers)
+	}
+	expanded := Expand(start, start.AddDate(0, 0, 2), endless)
+	if changeovers := GetChangeOvers(expanded...); !slicesEqual(changeovers, expected) {
+		t.Errorf("Expected %v but got %v", expected, changeovers)
+	}
+	if changeovers := GetChangeOversBetween(start, start.AddDate(0, 0, 2), endless); !slicesEqual(changeovers, expected) {
+		t.Errorf("Expected %v but got %v", expected, changeovers)
+	}
+	later := start.AddDate(1, 0, 0).Add(-time.Minute)
+	if next, err := GetNextChangeOver(later, endless); err != nil || !next.Equal(start.AddDate(1, 0, 0)) {
+		t.Errorf("Expected next changeover %v but got %v (%v)", start.AddDate(1, 0, 0), next, err)
+	}
+}
+
+func TestEndlessRecurringWithinPeriod(t *testing.T) {
+	start := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+	base := TimeWindow{
+		StartTime:  start,
+		EndTime:    start.AddDate(0, 0, 10),
+		Identifier: "base",
+	}
+	standup := RecurringPeriod{
+		StartTime:  start.Add(9 * time.Hour),
+		Duration:   time.Hour,
+		Identifier: "standup",
+		Recurrence: Recurrence{Frequency: Daily},
+	}
+	periods := []Period{base, standup}
+
+	r := NewResolver(periods...)
+	for ts := start.Add(-time.Hour); ts.Before(start.AddDate(0, 0, 12)); ts = ts.Add(30 * time.Minute) {
+		expected, expectedErr := MostSpecificPeriod(ts, periods...)
+		id, err := r.Resolve(ts)
+		if id != expected || err != expectedErr {
+			t.Fatalf("Resolve(%v) = %q, %v does not match expected %q, %v", ts, id, err, expected, expectedErr)
+		}
+	}
+
+	// the series is expanded up to the end of the base period
+	changeovers := GetChangeOvers(periods...)
+	if len(changeovers) != 22 {
+		t.Errorf("Expected 22 changeovers but got %d: %v", len(changeovers), changeovers)
+	}
+	timeline := GenerateTimeline(periods...)
+	if len(timeline) != 21 {
+		t.Fatalf("Expected 21 timeline entries but got %d", len(timeline))
+	}
+	if last := timeline[len(timeline)-1]; last.GetIdentifier() != "base" || !last.GetEndTime().Equal(base.EndTime) {
+		t.Errorf("Last timeline entry %v does not match expected base until %v", last, base.EndTime)
+	}
+
+	// beyond it, the series is expanded on demand
+	from := start.AddDate(0, 0, 20)
+	between := GenerateTimelineBetween(from, from.AddDate(0, 0, 2), periods...)
+	expected := []Period{
+		TimeWindow{StartTime: from.Add(9 * time.Hour), EndTime: from.Add(10 * time.Hour), Identifier: "standup"},
+		TimeWindow{StartTime: from.Add(33 * time.Hour), EndTime: from.Add(34 * time.Hour), Identifier: "standup"},
+	}
+	if len(between) != len(expected) {
+		t.Fatalf("Timeline %v does not match expected %v", between, expected)
+	}
+	for i := range expected {
+		if between[i].GetIdentifier() != expected[i].GetIdentifier() ||
+			!between[i].GetStartTime().Equal(expected[i].GetStartTime()) ||
+			!between[i].GetEndTime().Equal(expected[i].GetEndTime()) {
+			t.Errorf("Timeline entry %v does not match expected %v", between[i], expected[i])
+		}
+	}
+	next, err := NextChangeover(NewFakeClock(from), periods...)
+	if err != nil || !next.At.Equal(from.Add(9*time.Hour)) || next.From != "" || next.To != "standup" {
+		t.Errorf("Next changeover %+v (%v) does not match expected", next, err)
+	}
+}
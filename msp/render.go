@@ -0,0 +1,180 @@
+package msp
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+// defaultGanttWidth is the number of columns of the time axis when
+// GanttOptions.Width is not set.
+const defaultGanttWidth = 60
+
+// GanttOptions configures RenderGantt. The zero value renders all periods
+// under DefaultPolicy on a 60 column axis without escape sequences.
+type GanttOptions struct {
+	// Policy resolves the timeline row. The zero Policy is DefaultPolicy.
+	Policy Policy
+	// Width is the number of columns of the time axis.
+	Width int
+	// From and To limit the time axis. A zero time extends the axis to the
+	// earliest start or latest end of the periods respectively.
+	From time.Time
+	To   time.Time
+	// ANSI highlights the timeline row with terminal escape sequences.
+	ANSI bool
+}
+
+// RenderGantt draws each period as a bar on a shared time axis, one row per
+// period, followed by the resolved timeline of GenerateTimeline as a row
+// labelled MSP, each stretch starting with a |, and a row of ticks marking
+// the changeovers. Bars of periods unbounded towards either side run to the
+// edge of the axis, and recurring periods draw every occurrence on their
+// row. Nothing is drawn if the axis
+// cannot be determined, such as for periods without any bounds.
+func RenderGantt(w io.Writer, opts GanttOptions, periods ...Period) error {
+	from, to, ok := chartRange(opts.From, opts.To, periods)
+	if !ok {
+		return nil
+	}
+	width := opts.Width
+	if width <= 0 {
+		width = defaultGanttWidth
+	}
+	axis := ganttAxis{from: from, to: to, width: width}
+
+	labels := []string{"MSP"}
+	for _, p := range periods {
+		labels = append(labels, p.GetIdentifier())
+	}
+	labelWidth := 0
+	for _, l := range labels {
+		labelWidth = max(labelWidth, len([]rune(l)))
+	}
+	pad := func(label string) string {
+		return label + strings.Repeat(" ", labelWidth-len([]rune(label))) + " |"
+	}
+
+	var b strings.Builder
+	for i, p := range periods {
+		row := axis.blank()
+		for _, x := range expandBetween(from, to, []Period{p}) {
+			if !nonEmpty(x) {
+				continue
+			}
+			start, end := axis.columns(x.GetStartTime(), x.GetEndTime())
+			for c := start; c < end; c++ {
+				row[c] = '='
+			}
+			if x.GetStartTime().IsZero() || x.GetStartTime().Before(from) {
+				row[start] = '<'
+			}
+			if x.GetEndTime().IsZero() || x.GetEndTime().After(to) {
+				row[end-1] = '>'
+			}
+		}
+		b.WriteString(pad(labels[i+1]) + string(row) + "|\n")
+	}
+
+	row := axis.blank()
+	ticks := axis.blank()
+	for _, s := range TimelineBetweenBy(opts.Policy, from, to, periods...) {
+		start, end := axis.columns(s.StartTime, s.EndTime)
+		if !s.Gap {
+			// a separator keeps truncated labels of adjacent stretches apart
+			label := []rune("|" + s.GetIdentifier())
+			for c := start; c < end; c++ {
+				row[c] = '#'
+				if c-start < len(label) {
+					row[c] = label[c-start]
+				}
+			}
+		}
+	}
+	for _, c := range opts.Policy.chartChangeovers(from, to, periods) {
+		start, _ := axis.columns(c.At, c.At)
+		ticks[start] = '^'
+	}
+	timeline := string(row)
+	if opts.ANSI {
+		timeline = "\x1b[1;7m" + timeline + "\x1b[0m"
+	}
+	b.WriteString(pad(labels[0]) + timeline + "|\n")
+	b.WriteString(pad("") + string(ticks) + "|\n")
+
+	first := from.Format(time.RFC3339)
+	last := to.Format(time.RFC3339)
+	gap := max(width+2-len(first)-len(last), 1)
+	b.WriteString(strings.Repeat(" ", labelWidth+1) + first + strings.Repeat(" ", gap) + last + "\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ganttAxis maps times in [from, to) onto width columns.
+type ganttAxis struct {
+	from  time.Time
+	to    time.Time
+	width int
+}
+
+// blank returns an empty row.
+func (a ganttAxis) blank() []rune {
+	return []rune(strings.Repeat(" ", a.width))
+}
+
+// column returns the column containing t, clamped to the axis. Zero times
+// are unbounded: a zero start maps to the first column, a zero end past the
+// last.
+func (a ganttAxis) column(t time.Time, atEnd bool) int {
+	switch {
+	case t.IsZero() && atEnd, !t.Before(a.to):
+		return a.width
+	case t.IsZero(), !t.After(a.from):
+		return 0
+	}
+	return int(float64(t.Sub(a.from)) / float64(a.to.Sub(a.from)) * float64(a.width))
+}
+
+// columns returns the columns [start, end) covered by [from, to), covering
+// at least one column so that short periods remain visible.
+func (a ganttAxis) columns(from, to time.Time) (start, end int) {
+	start = min(a.column(from, false), a.width-1)
+	end = max(a.column(to, true), start+1)
+	return start, end
+}
+
+// chartRange returns the range covered by a chart of periods. Zero from or
+// to times are replaced by the earliest start or latest end of the periods
+// and their occurrences, up to the horizon of series without end. ok is
+// false if the range is empty or cannot be determined.
+func chartRange(from, to time.Time, periods []Period) (start, end time.Time, ok bool) {
+	start, end = from, to
+	instances, horizon := expandAll(periods)
+	for _, x := range instances {
+		for _, t := range []time.Time{x.GetStartTime(), x.GetEndTime()} {
+			if t.IsZero() {
+				continue
+			}
+			if from.IsZero() && (start.IsZero() || t.Before(start)) {
+				start = t
+			}
+			if to.IsZero() && (end.IsZero() || t.After(end)) {
+				end = t
+			}
+		}
+	}
+	if to.IsZero() && !horizon.IsZero() && end.After(horizon) {
+		end = horizon
+	}
+	if start.IsZero() || end.IsZero() || !start.Before(end) {
+		return start, end, false
+	}
+	return start, end, true
+}
+
+// chartChangeovers returns the changeovers drawn on a chart of [from, to):
+// those at or after from and before to.
+func (p Policy) chartChangeovers(from, to time.Time, periods []Period) []Changeover {
+	return p.changeoversBetween(from.Add(-time.Nanosecond), to.Add(-time.Nanosecond), periods)
+}
@@ -0,0 +1,79 @@
+package msp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderGantt(t *testing.T) {
+	day := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	d := 24 * time.Hour
+	testCases := []struct {
+		testID  string
+		opts    GanttOptions
+		periods []Period
+		result  []string
+	}{
+		{
+			testID:  "No periods",
+			opts:    GanttOptions{},
+			periods: []Period{},
+			result:  nil,
+		},
+		{
+			testID: "Overlapping, open-ended and recurring periods",
+			opts:   GanttOptions{Width: 30},
+			periods: []Period{
+				TimeWindow{StartTime: day, EndTime: day.Add(30 * d), Identifier: "month"},
+				TimeWindow{StartTime: day.Add(10 * d), EndTime: day.Add(17 * d), Identifier: "sale"},
+				TimeWindow{StartTime: day.Add(20 * d), Identifier: "open"},
+				RecurringPeriod{
+					StartTime:  day.Add(2 * d),
+					Duration:   d,
+					Identifier: "daily",
+					Recurrence: Recurrence{Frequency: Daily, Interval: 24, Count: 2},
+				},
+			},
+			result: []string{
+				"month |==============================|",
+				"sale  |          =======             |",
+				"open  |                    =========>|",
+				"daily |  =                       =   |",
+				"MSP   ||m||month#|sale##|month###||mo|",
+				"      |^ ^^      ^      ^        ^^  |",
+				"      2024-06-01T00:00:00Z 2024-07-01T00:00:00Z",
+			},
+		},
+		{
+			testID: "Clipped to a range",
+			opts:   GanttOptions{Width: 20, From: day.Add(5 * d), To: day.Add(15 * d)},
+			periods: []Period{
+				TimeWindow{StartTime: day, EndTime: day.Add(30 * d), Identifier: "month"},
+				TimeWindow{StartTime: day.Add(9 * d), EndTime: day.Add(12 * d), Identifier: "sale"},
+			},
+			result: []string{
+				"month |<==================>|",
+				"sale  |        ======      |",
+				"MSP   ||month##|sale#|month|",
+				"      |        ^     ^     |",
+				"      2024-06-06T00:00:00Z 2024-06-16T00:00:00Z",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			var b strings.Builder
+			if err := RenderGantt(&b, tc.opts, tc.periods...); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			var lines []string
+			if b.Len() > 0 {
+				lines = strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+			}
+			if !slicesEqual(lines, tc.result) {
+				t.Errorf("Expected chart\n%s\nbut got\n%s", strings.Join(tc.result, "\n"), b.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package msp
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// Resolver answers MostSpecificPeriod queries against a fixed set of periods.
+// The periods are flattened once into a sorted list of boundaries, so each
+// call to Resolve is a binary search rather than a scan of every period.
+type Resolver struct {
+	// bounds holds the distinct start and end times of all valid periods,
+	// in ascending order. Unset bounds of open-ended periods are omitted.
+	bounds []time.Time
+	// head is the most specific instance before bounds[0]. Its Period is
+	// nil when no period is active there.
+	head instance
+	// winners[i] is the most specific instance on [bounds[i], bounds[i+1]).
+	// Its Period is nil when no period is active there.
+	winners []instance
+	// horizon is how far recurring periods without end were expanded. From
+	// there on, lookup ranks the original periods directly. It is zero if
+	// there are no such periods.
+	horizon time.Time
+	policy  Policy
+	periods []Period
+}
+
+// NewResolver builds a Resolver for periods using DefaultPolicy. Periods
+// whose start time is not strictly before their end time can never be
+// selected and are dropped. Recurring periods are expanded into their
+// occurrences. A series without end is expanded up to the latest bound of the
+// other periods, and at least through its first occurrence; Resolve answers
+// queries beyond that horizon by ranking the periods directly.
+func NewResolver(periods ...Period) *Resolver {
+	return DefaultPolicy.NewResolver(periods...)
+}
+
+// NewResolver builds a Resolver for periods that ranks them under the
+// policy.
+func (p Policy) NewResolver(periods ...Period) *Resolver {
+	instances, horizon := expandAll(periods)
+	r := p.newResolver(instances)
+	if !horizon.IsZero() {
+		r.horizon, r.policy, r.periods = horizon, p, periods
+	}
+	return r
+}
+
+// newResolver builds a Resolver for already expanded instances.
+func (p Policy) newResolver(instances []instance) *Resolver {
+	var valid []instance
+	for _, x := range instances {
+		if nonEmpty(x) {
+			valid = append(valid, x)
+		}
+	}
+	r := &Resolver{}
+	if len(valid) == 0 {
+		return r
+	}
+	for _, x := range valid {
+		for _, b := range []time.Time{x.GetStartTime(), x.GetEndTime()} {
+			if !b.IsZero() {
+				r.bounds = append(r.bounds, b)
+			}
+		}
+	}
+	sort.Slice(r.bounds, func(i, j int) bool {
+		return r.bounds[i].Before(r.bounds[j])
+	})
+	r.bounds = compactTimes(r.bounds)
+
+	sort.SliceStable(valid, func(i, j int) bool {
+		return valid[i].GetStartTime().Before(valid[j].GetStartTime())
+	})
+	// Sweep the boundaries in order, keeping every period that has started in
+	// a heap ordered by specificity. Periods that have ended are discarded
+	// lazily once they reach the top.
+	active := &periodHeap{policy: p}
+	next := 0
+	for next < len(valid) && valid[next].GetStartTime().IsZero() {
+		heap.Push(active, valid[next])
+		next++
+	}
+	r.head = active.top()
+	r.winners = make([]instance, len(r.bounds))
+	for i, b := range r.bounds {
+		for next < len(valid) && !valid[next].GetStartTime().After(b) {
+			heap.Push(active, valid[next])
+			next++
+		}
+		for active.Len() > 0 && !contains(active.periods[0], b) {
+			heap.Pop(active)
+		}
+		r.winners[i] = active.top()
+	}
+	return r
+}
+
+// Resolve returns the identifier of the most specific period containing ts.
+// It always agrees with MostSpecificPeriod called with the periods and policy
+// the Resolver was built from, including beyond the horizon of a series
+// without end. If no period contains ts, ErrNoValidPeriods is returned.
+func (r *Resolver) Resolve(ts time.Time) (id string, err error) {
+	winner := r.lookup(ts)
+	if winner.Period == nil {
+		return "", ErrNoValidPeriods
+	}
+	return winner.GetIdentifier(), nil
+}
+
+// lookup returns the winning instance at ts. Its Period is nil if there is
+// none.
+func (r *Resolver) lookup(ts time.Time) instance {
+	if !r.horizon.IsZero() && !ts.Before(r.horizon) {
+		winner, _ := r.policy.winnerAt(ts, r.periods)
+		return winner
+	}
+	// index of the first boundary strictly after ts
+	i := sort.Search(len(r.bounds), func(i int) bool {
+		return r.bounds[i].After(ts)
+	})
+	if i == 0 {
+		return r.head
+	}
+	return r.winners[i-1]
+}
+
+// segments calls fn for each stretch of time over which the winner is the
+// same instance, in chronological order, up to the horizon if there is
+// one. start is zero for a stretch open towards the past and end is zero
+// for one open towards the future. winner.Period is nil for stretches where
+// no period is active.
+func (r *Resolver) segments(fn func(start, end time.Time, winner instance)) {
+	starts := []time.Time{{}}
+	winners := []instance{r.head}
+	for i, b := range r.bounds {
+		if !r.horizon.IsZero() && !b.Before(r.horizon) {
+			break
+		}
+		if sameInstance(r.winners[i], winners[len(winners)-1]) {
+			continue
+		}
+		starts = append(starts, b)
+		winners = append(winners, r.winners[i])
+	}
+	for i, start := range starts {
+		end := r.horizon
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		fn(start, end, winners[i])
+	}
+}
+
+// sameInstance reports whether a and b are both empty or the same instance
+// of the same input period.
+func sameInstance(a, b instance) bool {
+	if a.Period == nil || b.Period == nil {
+		return a.Period == nil && b.Period == nil
+	}
+	return a.index == b.index &&
+		a.GetStartTime().Equal(b.GetStartTime()) &&
+		a.GetEndTime().Equal(b.GetEndTime())
+}
+
+// compactTimes removes consecutive equal timestamps from a sorted slice.
+func compactTimes(ts []time.Time) []time.Time {
+	if len(ts) == 0 {
+		return ts
+	}
+	out := ts[:1]
+	for _, t := range ts[1:] {
+		if !t.Equal(out[len(out)-1]) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// periodHeap is a heap of periods with the most specific period on top.
+type periodHeap struct {
+	periods []instance
+	policy  Policy
+}
+
+func (h *periodHeap) Len() int { return len(h.periods) }
+
+// top returns the most specific instance in the heap, or the zero instance
+// if it is empty.
+func (h *periodHeap) top() instance {
+	if len(h.periods) == 0 {
+		return instance{}
+	}
+	return h.periods[0]
+}
+
+func (h *periodHeap) Less(i, j int) bool {
+	if c := h.policy.Compare(h.periods[i].Period, h.periods[j].Period); c != 0 {
+		return c < 0
+	}
+	return h.periods[i].index < h.periods[j].index
+}
+
+func (h *periodHeap) Swap(i, j int) {
+	h.periods[i], h.periods[j] = h.periods[j], h.periods[i]
+}
+
+func (h *periodHeap) Push(x any) {
+	h.periods = append(h.periods, x.(instance))
+}
+
+func (h *periodHeap) Pop() any {
+	n := len(h.periods)
+	x := h.periods[n-1]
+	h.periods = h.periods[:n-1]
+	return x
+}
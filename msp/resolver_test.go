@@ -0,0 +1,194 @@
+package msp
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestResolver(t *testing.T) {
+	// use a static timestamp to make sure tests don't fail on slower systems or during a process pause
+	now := time.Now()
+	testCases := []struct {
+		ts      time.Time
+		testID  string
+		result  string
+		err     error
+		periods []Period
+	}{
+		{
+			testID:  "No choices",
+			ts:      now,
+			result:  "",
+			err:     ErrNoValidPeriods,
+			periods: []Period{},
+		},
+		{
+			testID: "Two Choices, shorter is second",
+			ts:     now,
+			result: "B",
+			err:    nil,
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-5 * time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-2 * time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "B",
+				},
+			},
+		},
+		{
+			testID: "Two Choices, one in the past",
+			ts:     now,
+			result: "A",
+			err:    nil,
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-2 * time.Minute),
+					EndTime:    now.Add(-time.Minute),
+					Identifier: "B",
+				},
+			},
+		},
+		{
+			testID: "Two Choices, one invalid",
+			ts:     now,
+			result: "B",
+			err:    nil,
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(time.Minute),
+					EndTime:    now.Add(-time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-2 * time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "B",
+				},
+			},
+		},
+		{
+			testID: "Two Choices, Identical periods",
+			ts:     now,
+			result: "B",
+			err:    nil,
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "B",
+				},
+			},
+		},
+		{
+			testID: "Same duration, later start wins",
+			ts:     now,
+			result: "B",
+			err:    nil,
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-2 * time.Minute),
+					EndTime:    now.Add(2 * time.Minute),
+					Identifier: "B",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-3 * time.Minute),
+					EndTime:    now.Add(time.Minute),
+					Identifier: "C",
+				},
+			},
+		},
+		{
+			testID: "Timestamp on an end boundary",
+			ts:     now,
+			result: "A",
+			err:    nil,
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(-10 * time.Minute),
+					EndTime:    now.Add(10 * time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(-time.Minute),
+					EndTime:    now,
+					Identifier: "B",
+				},
+			},
+		},
+		{
+			testID: "Timestamp before every period",
+			ts:     now,
+			result: "",
+			err:    ErrNoValidPeriods,
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Add(time.Minute),
+					EndTime:    now.Add(2 * time.Minute),
+					Identifier: "A",
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			id, err := NewResolver(tc.periods...).Resolve(tc.ts)
+			if id != tc.result {
+				t.Errorf("ID '%s' does not match expected '%s'", id, tc.result)
+			}
+			if err != tc.err {
+				t.Errorf("Error '%v' does not match expected '%v'", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestResolverMatchesMostSpecificPeriod(t *testing.T) {
+	now := time.Now()
+	rng := rand.New(rand.NewSource(1))
+	ids := []string{"A", "B", "C", "D"}
+	for round := 0; round < 200; round++ {
+		var periods []Period
+		for i := rng.Intn(8); i >= 0; i-- {
+			start := now.Add(time.Duration(rng.Intn(20)) * time.Minute)
+			end := start.Add(time.Duration(rng.Intn(12)-2) * time.Minute)
+			// occasionally leave a side unbounded
+			switch rng.Intn(8) {
+			case 0:
+				start = time.Time{}
+			case 1:
+				end = time.Time{}
+			}
+			periods = append(periods, TimeWindow{
+				StartTime:  start,
+				EndTime:    end,
+				Identifier: ids[rng.Intn(len(ids))],
+			})
+		}
+		r := NewResolver(periods...)
+		for m := -1; m < 32; m++ {
+			ts := now.Add(time.Duration(m) * time.Minute)
+			want, wantErr := MostSpecificPeriod(ts, periods...)
+			got, gotErr := r.Resolve(ts)
+			if got != want || gotErr != wantErr {
+				t.Fatalf("round %d at %v: Resolve returned (%q, %v), MostSpecificPeriod returned (%q, %v)",
+					round, ts, got, gotErr, want, wantErr)
+			}
+		}
+	}
+}
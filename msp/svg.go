@@ -0,0 +1,179 @@
+package msp
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// Layout of the SVG chart in pixels.
+const (
+	defaultSVGWidth = 800
+	svgLabelWidth   = 140
+	svgLaneHeight   = 24
+	svgLaneGap      = 6
+	svgMargin       = 10
+)
+
+// svgPalette colors periods by identifier.
+var svgPalette = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f",
+	"#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac",
+}
+
+// SVGOptions configures RenderSVG and RenderHTML. The zero value renders
+// all periods under DefaultPolicy, 800 pixels wide, without a cursor.
+type SVGOptions struct {
+	// Policy resolves the timeline lane. The zero Policy is DefaultPolicy.
+	Policy Policy
+	// Width is the width of the image in pixels.
+	Width int
+	// From and To limit the time axis. A zero time extends the axis to the
+	// earliest start or latest end of the periods respectively.
+	From time.Time
+	To   time.Time
+	// Now draws a cursor at the given time unless it is zero.
+	Now time.Time
+	// Title is shown above the chart by RenderHTML and used as the title
+	// of the image.
+	Title string
+}
+
+// RenderSVG writes an SVG image showing each period as a lane of bars, the
+// resolved timeline of GenerateTimeline as a final lane, dashed lines at the
+// changeovers of GetChangeOvers before the end of the axis, as on the ticks
+// row of RenderGantt, and, optionally, a cursor at opts.Now. Bars share a
+// color per identifier and carry their bounds as a tooltip. An image without
+// lanes is written if the axis cannot be determined.
+func RenderSVG(w io.Writer, opts SVGOptions, periods ...Period) error {
+	_, err := io.WriteString(w, svgDocument(opts, periods))
+	return err
+}
+
+// RenderHTML writes a self-contained HTML page embedding the image of
+// RenderSVG.
+func RenderHTML(w io.Writer, opts SVGOptions, periods ...Period) error {
+	title := opts.Title
+	if title == "" {
+		title = "Most specific period"
+	}
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(title))
+	b.WriteString("<style>body { font-family: sans-serif; margin: 2em; }</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(title))
+	b.WriteString(svgDocument(opts, periods))
+	b.WriteString("</body>\n</html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// svgDocument returns the SVG image for RenderSVG.
+func svgDocument(opts SVGOptions, periods []Period) string {
+	width := opts.Width
+	if width <= 0 {
+		width = defaultSVGWidth
+	}
+	from, to, ok := chartRange(opts.From, opts.To, periods)
+	lanes := 0
+	if ok {
+		lanes = len(periods) + 1
+	}
+	laneY := func(i int) int {
+		return svgMargin + i*(svgLaneHeight+svgLaneGap)
+	}
+	axisY := laneY(lanes)
+	height := axisY + 2*svgMargin + 12
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"sans-serif\" font-size=\"12\">\n",
+		width, height, width, height)
+	if opts.Title != "" {
+		fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(opts.Title))
+	}
+	if !ok {
+		b.WriteString("</svg>\n")
+		return b.String()
+	}
+
+	plot := float64(width - svgLabelWidth - svgMargin)
+	x := func(t time.Time, atEnd bool) float64 {
+		switch {
+		case t.IsZero() && atEnd, !t.Before(to):
+			return svgLabelWidth + plot
+		case t.IsZero(), !t.After(from):
+			return svgLabelWidth
+		}
+		return svgLabelWidth + float64(t.Sub(from))/float64(to.Sub(from))*plot
+	}
+	bar := func(y int, id string, start, end time.Time, class string) {
+		x0, x1 := x(start, false), x(end, true)
+		fmt.Fprintf(&b, "<rect class=\"%s\" x=\"%.2f\" y=\"%d\" width=\"%.2f\" height=\"%d\" rx=\"3\" fill=\"%s\"><title>%s: %s – %s</title></rect>\n",
+			class, x0, y, max(x1-x0, 1), svgLaneHeight, svgColor(id), html.EscapeString(id), svgTime(start), svgTime(end))
+	}
+	label := func(y int, text string, bold bool) {
+		weight := ""
+		if bold {
+			weight = " font-weight=\"bold\""
+		}
+		fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\" dominant-baseline=\"middle\"%s>%s</text>\n",
+			svgMargin, y+svgLaneHeight/2, weight, html.EscapeString(text))
+	}
+
+	for i, p := range periods {
+		y := laneY(i)
+		label(y, p.GetIdentifier(), false)
+		for _, o := range expandBetween(from, to, []Period{p}) {
+			if nonEmpty(o) {
+				bar(y, o.GetIdentifier(), o.GetStartTime(), o.GetEndTime(), "period")
+			}
+		}
+	}
+	y := laneY(len(periods))
+	label(y, "MSP", true)
+	for _, s := range WithoutGaps(TimelineBetweenBy(opts.Policy, from, to, periods...)) {
+		bar(y, s.GetIdentifier(), s.StartTime, s.EndTime, "timeline")
+	}
+
+	for _, c := range opts.Policy.chartChangeovers(from, to, periods) {
+		fmt.Fprintf(&b, "<line class=\"changeover\" x1=\"%.2f\" y1=\"%d\" x2=\"%.2f\" y2=\"%d\" stroke=\"#555\" stroke-dasharray=\"4 3\"><title>%s: %s → %s</title></line>\n",
+			x(c.At, false), svgMargin, x(c.At, false), axisY, svgTime(c.At), html.EscapeString(svgName(c.From)), html.EscapeString(svgName(c.To)))
+	}
+	if !opts.Now.IsZero() && !opts.Now.Before(from) && !opts.Now.After(to) {
+		fmt.Fprintf(&b, "<line class=\"now\" x1=\"%.2f\" y1=\"%d\" x2=\"%.2f\" y2=\"%d\" stroke=\"#d00\" stroke-width=\"2\"><title>now: %s</title></line>\n",
+			x(opts.Now, false), svgMargin/2, x(opts.Now, false), axisY, svgTime(opts.Now))
+	}
+
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\" dominant-baseline=\"hanging\">%s</text>\n", svgLabelWidth, axisY+svgMargin, svgTime(from))
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\" dominant-baseline=\"hanging\" text-anchor=\"end\">%s</text>\n", width-svgMargin, axisY+svgMargin, svgTime(to))
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// svgColor returns the palette color for an identifier.
+func svgColor(id string) string {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return svgPalette[h.Sum32()%uint32(len(svgPalette))]
+}
+
+// svgTime formats a time for labels and tooltips, using "unbounded" for the
+// zero time.
+func svgTime(t time.Time) string {
+	if t.IsZero() {
+		return "unbounded"
+	}
+	return t.Format(time.RFC3339)
+}
+
+// svgName returns id, or "none" for the empty identifier of a changeover
+// from or to no period.
+func svgName(id string) string {
+	if id == "" {
+		return "none"
+	}
+	return id
+}
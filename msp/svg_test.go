@@ -0,0 +1,121 @@
+package msp
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderSVG(t *testing.T) {
+	day := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	d := 24 * time.Hour
+	testCases := []struct {
+		testID      string
+		opts        SVGOptions
+		periods     []Period
+		bars        int
+		segments    int
+		changeovers int
+		now         bool
+	}{
+		{
+			testID:  "No periods",
+			opts:    SVGOptions{},
+			periods: []Period{},
+		},
+		{
+			testID: "Overlapping and recurring periods",
+			opts:   SVGOptions{},
+			periods: []Period{
+				TimeWindow{StartTime: day, EndTime: day.Add(30 * d), Identifier: "month"},
+				TimeWindow{StartTime: day.Add(10 * d), EndTime: day.Add(17 * d), Identifier: "<sale>"},
+				RecurringPeriod{
+					StartTime:  day.Add(2 * d),
+					Duration:   d,
+					Identifier: "daily & more",
+					Recurrence: Recurrence{Frequency: Daily, Interval: 24, Count: 2},
+				},
+			},
+			bars:        4,
+			segments:    7,
+			changeovers: 7,
+		},
+		{
+			testID: "Clipped with a cursor",
+			opts:   SVGOptions{From: day.Add(5 * d), To: day.Add(15 * d), Now: day.Add(11 * d), Title: "June"},
+			periods: []Period{
+				TimeWindow{StartTime: day, EndTime: day.Add(30 * d), Identifier: "month"},
+				TimeWindow{StartTime: day.Add(9 * d), EndTime: day.Add(12 * d), Identifier: "sale"},
+			},
+			bars:        2,
+			segments:    3,
+			changeovers: 2,
+			now:         true,
+		},
+		{
+			testID: "Cursor outside the range",
+			opts:   SVGOptions{Now: day.Add(-d)},
+			periods: []Period{
+				TimeWindow{StartTime: day, EndTime: day.Add(30 * d), Identifier: "month"},
+			},
+			bars:        1,
+			segments:    1,
+			changeovers: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			var b strings.Builder
+			if err := RenderSVG(&b, tc.opts, tc.periods...); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			counts := map[string]int{}
+			dec := xml.NewDecoder(strings.NewReader(b.String()))
+			for {
+				tok, err := dec.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Invalid SVG: %v\n%s", err, b.String())
+				}
+				if el, ok := tok.(xml.StartElement); ok {
+					for _, a := range el.Attr {
+						if a.Name.Local == "class" {
+							counts[a.Value]++
+						}
+					}
+				}
+			}
+			if counts["period"] != tc.bars {
+				t.Errorf("Drew %d period bars, expected %d", counts["period"], tc.bars)
+			}
+			if counts["timeline"] != tc.segments {
+				t.Errorf("Drew %d timeline segments, expected %d", counts["timeline"], tc.segments)
+			}
+			if counts["changeover"] != tc.changeovers {
+				t.Errorf("Drew %d changeovers, expected %d", counts["changeover"], tc.changeovers)
+			}
+			if (counts["now"] == 1) != tc.now {
+				t.Errorf("Drew %d cursors, expected %v", counts["now"], tc.now)
+			}
+		})
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	day := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	var b strings.Builder
+	err := RenderHTML(&b, SVGOptions{Title: "Q&A"}, TimeWindow{StartTime: day, EndTime: day.Add(time.Hour), Identifier: "A"})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	page := b.String()
+	for _, want := range []string{"<!DOCTYPE html>", "<title>Q&amp;A</title>", "<svg ", "</svg>", "</html>"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Page does not contain %q:\n%s", want, page)
+		}
+	}
+}
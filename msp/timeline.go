@@ -5,11 +5,15 @@ import (
 	"time"
 )
 
-// TimeWindow is a concrete implementation of the Period interface.
+// TimeWindow is a concrete implementation of the Period interface. A zero
+// StartTime or EndTime leaves the window unbounded on that side, and Bounds
+// says whether the window contains its start and end times.
 type TimeWindow struct {
 	StartTime  time.Time
 	EndTime    time.Time
 	Identifier string
+	Priority   int
+	Bounds     Bounds
 }
 
 // GetIdentifier returns the period's identifier string.
@@ -17,16 +21,26 @@ func (p TimeWindow) GetIdentifier() string {
 	return p.Identifier
 }
 
-// GetEndTime returns the period's exclusive end time.
+// GetEndTime returns the period's end time.
 func (p TimeWindow) GetEndTime() time.Time {
 	return p.EndTime
 }
 
-// GetStartTime returns the period's inclusive start time.
+// GetStartTime returns the period's start time.
 func (p TimeWindow) GetStartTime() time.Time {
 	return p.StartTime
 }
 
+// GetPriority returns the period's explicit priority.
+func (p TimeWindow) GetPriority() int {
+	return p.Priority
+}
+
+// GetBounds returns whether the window contains its start and end times.
+func (p TimeWindow) GetBounds() Bounds {
+	return p.Bounds
+}
+
 // String returns a tab-separated representation of the time window.
 func (t TimeWindow) String() string {
 	return fmt.Sprintf("%s\t%s\t%s",
@@ -36,28 +50,45 @@ func (t TimeWindow) String() string {
 }
 
 // GenerateTimeline produces a flattened timeline of non-overlapping periods
-// by splitting overlapping input periods at changeover points.
+// by splitting overlapping input periods at changeover points. Each entry is
+// a TimeWindow carrying the identifier and priority of the period instance
+// that wins across it; stretches where no period is active are omitted. Use
+// Timeline to get the winning periods themselves along with gaps. Recurring
+// periods without end are expanded up to the horizon described at
+// NewResolver; use GenerateTimelineBetween to choose the range instead.
 func GenerateTimeline(periods ...Period) (out []Period) {
-	if len(periods) == 0 {
-		return out
-	}
-	periodsByID := make(map[string]Period)
-	ids := FlattenPeriods(periods...)
-	for _, val := range periods {
-		id := val.GetIdentifier()
-		periodsByID[id] = val
-	}
-	start := periodsByID[ids[0]].GetStartTime()
-	for _, val := range ids {
-		next, err := GetNextChangeOver(start, periods...)
-		if err == nil {
-			if next.Equal(periodsByID[val].GetStartTime()) {
-				start = periodsByID[val].GetStartTime()
-				next = periodsByID[val].GetEndTime()
-			}
-			out = append(out, TimeWindow{StartTime: start, EndTime: next, Identifier: val})
-			start = next
-		}
+	return DefaultPolicy.GenerateTimeline(periods...)
+}
+
+// GenerateTimeline produces a flattened timeline of non-overlapping periods,
+// ranking overlapping input periods under the policy.
+func (p Policy) GenerateTimeline(periods ...Period) (out []Period) {
+	return timeWindows(WithoutGaps(TimelineBy(p, periods...)))
+}
+
+// GenerateTimelineBetween is like GenerateTimeline but only covers
+// [from, to). Recurring periods are expanded within the range, so series
+// without end need no further bounding.
+func GenerateTimelineBetween(from, to time.Time, periods ...Period) (out []Period) {
+	return DefaultPolicy.GenerateTimelineBetween(from, to, periods...)
+}
+
+// GenerateTimelineBetween is like GenerateTimeline but only covers
+// [from, to).
+func (p Policy) GenerateTimelineBetween(from, to time.Time, periods ...Period) (out []Period) {
+	return timeWindows(WithoutGaps(TimelineBetweenBy(p, from, to, periods...)))
+}
+
+// timeWindows converts segments into TimeWindows carrying the identifier and
+// priority of their periods.
+func timeWindows(segments []Segment[Period]) (out []Period) {
+	for _, s := range segments {
+		out = append(out, TimeWindow{
+			StartTime:  s.StartTime,
+			EndTime:    s.EndTime,
+			Identifier: s.GetIdentifier(),
+			Priority:   s.GetPriority(),
+		})
 	}
 	return out
 }
@@ -2,6 +2,7 @@ package msp
 
 import (
 	"fmt"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -276,3 +277,336 @@ func TestGenerateTime(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateTimelineOpenEnded(t *testing.T) {
+	now := time.Now()
+	periods := []Period{
+		TimeWindow{
+			Identifier: "default",
+		},
+		TimeWindow{
+			StartTime:  now,
+			EndTime:    now.Add(time.Hour),
+			Identifier: "promo",
+		},
+	}
+	expected := []string{
+		fmt.Sprintf("default\t%s\t%s", time.Time{}, now),
+		fmt.Sprintf("promo\t%s\t%s", now, now.Add(time.Hour)),
+		fmt.Sprintf("default\t%s\t%s", now.Add(time.Hour), time.Time{}),
+	}
+	timeline := GenerateTimeline(periods...)
+	if len(timeline) != len(expected) {
+		t.Fatalf("Time line had %d results, expected %d", len(timeline), len(expected))
+	}
+	for idx, period := range timeline {
+		if period.(TimeWindow).String() != expected[idx] {
+			t.Errorf("Expected:\t%s\nHad:\t%s", expected[idx], period)
+		}
+	}
+}
+
+func TestGenerateTimelineDuplicateIdentifiers(t *testing.T) {
+	year := func(y int) time.Time {
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	holiday := func(y int) time.Time {
+		return time.Date(y, time.December, 24, 0, 0, 0, 0, time.UTC)
+	}
+	periods := []Period{
+		TimeWindow{
+			StartTime:  year(2023),
+			EndTime:    year(2025),
+			Identifier: "standard",
+		},
+		TimeWindow{
+			StartTime:  holiday(2023),
+			EndTime:    holiday(2023).AddDate(0, 0, 3),
+			Identifier: "holiday",
+		},
+		TimeWindow{
+			StartTime:  holiday(2024),
+			EndTime:    holiday(2024).AddDate(0, 0, 3),
+			Identifier: "holiday",
+		},
+	}
+	expected := []string{
+		fmt.Sprintf("standard\t%s\t%s", year(2023), holiday(2023)),
+		fmt.Sprintf("holiday\t%s\t%s", holiday(2023), holiday(2023).AddDate(0, 0, 3)),
+		fmt.Sprintf("standard\t%s\t%s", holiday(2023).AddDate(0, 0, 3), holiday(2024)),
+		fmt.Sprintf("holiday\t%s\t%s", holiday(2024), holiday(2024).AddDate(0, 0, 3)),
+		fmt.Sprintf("standard\t%s\t%s", holiday(2024).AddDate(0, 0, 3), year(2025)),
+	}
+	timeline := GenerateTimeline(periods...)
+	if len(timeline) != len(expected) {
+		t.Fatalf("Time line had %d results, expected %d", len(timeline), len(expected))
+	}
+	for idx, period := range timeline {
+		if period.(TimeWindow).String() != expected[idx] {
+			t.Errorf("Expected:\t%s\nHad:\t%s", expected[idx], period)
+		}
+	}
+}
+
+func TestTimelineGaps(t *testing.T) {
+	now := time.Now()
+	periods := []Period{
+		TimeWindow{
+			StartTime:  now.Add(-time.Minute * 10),
+			EndTime:    now.Add(-time.Minute * 5),
+			Identifier: "A",
+		},
+		TimeWindow{
+			StartTime:  now.Add(time.Minute * 5),
+			EndTime:    now.Add(time.Minute * 10),
+			Identifier: "B",
+		},
+	}
+	timeline := Timeline(periods...)
+	if len(timeline) != 3 {
+		t.Fatalf("Time line had %d results, expected 3", len(timeline))
+	}
+	gap := timeline[1]
+	if !gap.Gap || gap.GetIdentifier() != "" || !gap.StartTime.Equal(now.Add(-time.Minute*5)) || !gap.EndTime.Equal(now.Add(time.Minute*5)) {
+		t.Errorf("Unexpected gap segment %+v", gap)
+	}
+}
+
+// randomPeriods returns a random mix of periods around now, some of them
+// sharing identifiers, open-ended, inverted or recurring.
+func randomPeriods(rng *rand.Rand, now time.Time) []Period {
+	ids := []string{"A", "B", "C", "D"}
+	var periods []Period
+	for i := rng.Intn(8); i >= 0; i-- {
+		start := now.Add(time.Duration(rng.Intn(40)) * time.Minute)
+		end := start.Add(time.Duration(rng.Intn(20)-2) * time.Minute)
+		id := ids[rng.Intn(len(ids))]
+		switch rng.Intn(10) {
+		case 0:
+			start = time.Time{}
+		case 1:
+			end = time.Time{}
+		case 2:
+			periods = append(periods, RecurringPeriod{
+				StartTime:  start,
+				Duration:   time.Duration(rng.Intn(5)+1) * time.Minute,
+				Identifier: id,
+				Bounds:     Bounds(rng.Intn(4)),
+				Recurrence: Recurrence{Frequency: Daily, Count: rng.Intn(3) + 1},
+			})
+			continue
+		}
+		periods = append(periods, TimeWindow{
+			StartTime:  start,
+			EndTime:    end,
+			Identifier: id,
+			Priority:   rng.Intn(3) / 2,
+			Bounds:     Bounds(rng.Intn(4)),
+		})
+	}
+	return periods
+}
+
+// boundaries returns every start and end time of periods and their
+// occurrences, both as given and as adjusted for their bounds.
+func boundaries(periods []Period) []time.Time {
+	var out []time.Time
+	instances, _ := expandAll(periods)
+	for _, x := range instances {
+		for _, b := range []time.Time{x.GetStartTime(), x.GetEndTime(), x.Period.GetStartTime(), x.Period.GetEndTime()} {
+			if !b.IsZero() {
+				out = append(out, b, b.Add(-time.Nanosecond))
+			}
+		}
+	}
+	return out
+}
+
+func TestTimelineProperties(t *testing.T) {
+	now := time.Now()
+	rng := rand.New(rand.NewSource(7))
+	for round := 0; round < 500; round++ {
+		periods := randomPeriods(rng, now)
+		timeline := Timeline(periods...)
+
+		// segments are ordered, contiguous with their gaps, non-empty and
+		// within the bounds of their period
+		for i, s := range timeline {
+			if !s.StartTime.IsZero() && !s.EndTime.IsZero() && !s.StartTime.Before(s.EndTime) {
+				t.Fatalf("round %d: empty segment %+v", round, s)
+			}
+			if i > 0 && !timeline[i-1].EndTime.Equal(s.StartTime) {
+				t.Fatalf("round %d: segment %+v does not follow %+v", round, s, timeline[i-1])
+			}
+			if s.Gap {
+				continue
+			}
+			if start := startOf(s.Period); start.After(s.StartTime) {
+				t.Fatalf("round %d: segment %+v starts before its period", round, s)
+			}
+			if end := endOf(s.Period); !end.IsZero() && end.Before(s.EndTime) {
+				t.Fatalf("round %d: segment %+v ends after its period", round, s)
+			}
+		}
+
+		// every boundary resolves to the segment containing it
+		for _, ts := range boundaries(periods) {
+			want, err := MostSpecificPeriod(ts, periods...)
+			got := ""
+			found := false
+			for _, s := range timeline {
+				if contains(s, ts) {
+					got = s.GetIdentifier()
+					found = !s.Gap
+					break
+				}
+			}
+			if found != (err == nil) || got != want {
+				t.Fatalf("round %d at %v: timeline has %q (found %v), MostSpecificPeriod returned %q (%v)\nperiods: %v\ntimeline: %v",
+					round, ts, got, found, want, err, periods, timeline)
+			}
+		}
+
+		// the legacy timeline is the same minus the gaps
+		legacy := GenerateTimeline(periods...)
+		segments := WithoutGaps(timeline)
+		if len(legacy) != len(segments) {
+			t.Fatalf("round %d: GenerateTimeline had %d entries, Timeline %d", round, len(legacy), len(segments))
+		}
+		for i, s := range segments {
+			if legacy[i].GetIdentifier() != s.GetIdentifier() || !legacy[i].GetStartTime().Equal(s.StartTime) || !legacy[i].GetEndTime().Equal(s.EndTime) {
+				t.Fatalf("round %d: GenerateTimeline entry %v does not match segment %+v", round, legacy[i], s)
+			}
+		}
+	}
+}
+
+func TestTimelineBetween(t *testing.T) {
+	// strip the monotonic reading, which occurrences do not carry
+	now := time.Now().Round(0)
+	periods := []Period{
+		TimeWindow{
+			StartTime:  now.Add(-time.Hour),
+			EndTime:    now.Add(time.Hour),
+			Identifier: "A",
+		},
+		TimeWindow{
+			StartTime:  now.Add(-time.Minute),
+			EndTime:    now.Add(time.Minute),
+			Identifier: "B",
+		},
+		RecurringPeriod{
+			StartTime:  now.Add(-21 * time.Hour),
+			Duration:   time.Minute,
+			Identifier: "C",
+			Recurrence: Recurrence{Frequency: Daily},
+		},
+	}
+	testCases := []struct {
+		testID string
+		from   time.Time
+		to     time.Time
+		result []string
+	}{
+		{
+			testID: "Empty range",
+			from:   now,
+			to:     now,
+			result: nil,
+		},
+		{
+			testID: "Inside a single segment",
+			from:   now.Add(-30 * time.Second),
+			to:     now.Add(30 * time.Second),
+			result: []string{
+				fmt.Sprintf("B\t%s\t%s", now.Add(-30*time.Second), now.Add(30*time.Second)),
+			},
+		},
+		{
+			testID: "Clipped at both ends",
+			from:   now.Add(-2 * time.Minute),
+			to:     now.Add(2 * time.Minute),
+			result: []string{
+				fmt.Sprintf("A\t%s\t%s", now.Add(-2*time.Minute), now.Add(-time.Minute)),
+				fmt.Sprintf("B\t%s\t%s", now.Add(-time.Minute), now.Add(time.Minute)),
+				fmt.Sprintf("A\t%s\t%s", now.Add(time.Minute), now.Add(2*time.Minute)),
+			},
+		},
+		{
+			testID: "Gaps at both ends",
+			from:   now.Add(-2 * time.Hour),
+			to:     now.Add(2 * time.Hour),
+			result: []string{
+				fmt.Sprintf("\t%s\t%s", now.Add(-2*time.Hour), now.Add(-time.Hour)),
+				fmt.Sprintf("A\t%s\t%s", now.Add(-time.Hour), now.Add(-time.Minute)),
+				fmt.Sprintf("B\t%s\t%s", now.Add(-time.Minute), now.Add(time.Minute)),
+				fmt.Sprintf("A\t%s\t%s", now.Add(time.Minute), now.Add(time.Hour)),
+				fmt.Sprintf("\t%s\t%s", now.Add(time.Hour), now.Add(2*time.Hour)),
+			},
+		},
+		{
+			testID: "Endless recurrence expanded within the range",
+			from:   now.Add(26 * time.Hour),
+			to:     now.Add(28 * time.Hour),
+			result: []string{
+				fmt.Sprintf("\t%s\t%s", now.Add(26*time.Hour), now.Add(27*time.Hour)),
+				fmt.Sprintf("C\t%s\t%s", now.Add(27*time.Hour), now.Add(27*time.Hour+time.Minute)),
+				fmt.Sprintf("\t%s\t%s", now.Add(27*time.Hour+time.Minute), now.Add(28*time.Hour)),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			timeline := TimelineBetween(tc.from, tc.to, periods...)
+			if len(timeline) != len(tc.result) {
+				t.Fatalf("Time line had %d results, expected %d: %v", len(timeline), len(tc.result), timeline)
+			}
+			for idx, s := range timeline {
+				got := fmt.Sprintf("%s\t%s\t%s", s.GetIdentifier(), s.StartTime, s.EndTime)
+				if got != tc.result[idx] {
+					t.Errorf("Expected:\t%s\nHad:\t%s", tc.result[idx], got)
+				}
+			}
+		})
+	}
+}
+
+func TestTimelineBetweenProperties(t *testing.T) {
+	now := time.Now()
+	rng := rand.New(rand.NewSource(11))
+	for round := 0; round < 500; round++ {
+		periods := randomPeriods(rng, now)
+		from := now.Add(time.Duration(rng.Intn(40)-5) * time.Minute)
+		to := from.Add(time.Duration(rng.Intn(30)+1) * time.Minute)
+		timeline := TimelineBetween(from, to, periods...)
+
+		// the segments exactly cover [from, to)
+		if len(timeline) == 0 || !timeline[0].StartTime.Equal(from) || !timeline[len(timeline)-1].EndTime.Equal(to) {
+			t.Fatalf("round %d: timeline %v does not cover [%v, %v)", round, timeline, from, to)
+		}
+		for i, s := range timeline {
+			if !s.StartTime.Before(s.EndTime) {
+				t.Fatalf("round %d: empty segment %+v", round, s)
+			}
+			if i > 0 && !timeline[i-1].EndTime.Equal(s.StartTime) {
+				t.Fatalf("round %d: segment %+v does not follow %+v", round, s, timeline[i-1])
+			}
+		}
+
+		// every boundary within the range resolves to its segment
+		for _, ts := range append(boundaries(periods), from, to.Add(-time.Nanosecond)) {
+			if ts.Before(from) || !ts.Before(to) {
+				continue
+			}
+			want, err := MostSpecificPeriod(ts, periods...)
+			for _, s := range timeline {
+				if !contains(s, ts) {
+					continue
+				}
+				if s.Gap != (err != nil) || s.GetIdentifier() != want {
+					t.Fatalf("round %d at %v: segment %+v, MostSpecificPeriod returned %q (%v)", round, ts, s, want, err)
+				}
+			}
+		}
+	}
+}
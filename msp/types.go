@@ -5,12 +5,33 @@ package msp
 
 import "time"
 
-// Compile-time interface check.
-var _ Period = TimeWindow{}
+// Compile-time interface checks.
+var (
+	_ Period      = TimeWindow{}
+	_ Prioritized = TimeWindow{}
+	_ Bounded     = TimeWindow{}
+)
 
-// Period represents a named time window with inclusive start and exclusive end.
+// Period represents a named time window with inclusive start and exclusive end,
+// unless it implements Bounded. A zero start or end time means the period is
+// unbounded on that side.
 type Period interface {
 	GetStartTime() time.Time
 	GetEndTime() time.Time
 	GetIdentifier() string
 }
+
+// Prioritized is implemented by periods that carry an explicit priority.
+// Under DefaultPolicy a higher priority wins before duration is considered.
+// Periods that do not implement Prioritized have priority 0.
+type Prioritized interface {
+	GetPriority() int
+}
+
+// GetPriority returns p's priority if it implements Prioritized, or 0.
+func GetPriority(p Period) int {
+	if x, ok := p.(Prioritized); ok {
+		return x.GetPriority()
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package msp
+
+import (
+	"errors"
+	"time"
+)
+
+// Validate checks every period for inverted bounds, zero length, an empty
+// identifier and start and end times in different locations. It returns nil
+// if no problem is found, or every problem as a *ValidationError joined with
+// errors.Join. MostSpecificPeriod and friends skip inverted and zero-length
+// periods silently, so Validate is the way to find out about them. The
+// occurrences of a RecurringPeriod are checked as well.
+func Validate(periods ...Period) error {
+	var errs []error
+	for i, p := range periods {
+		for _, err := range problems(p) {
+			errs = append(errs, &ValidationError{Index: i, Identifier: p.GetIdentifier(), Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// problems returns the sentinel errors describing what is wrong with p.
+func problems(p Period) []error {
+	var errs []error
+	if r, ok := p.(RecurringPeriod); ok && !r.StartTime.IsZero() {
+		// every occurrence lasts Duration, so the first one stands for all
+		errs = append(errs, boundsProblems(TimeWindow{
+			StartTime: r.StartTime,
+			EndTime:   r.StartTime.Add(r.Duration),
+			Bounds:    r.Bounds,
+		})...)
+	}
+	start := p.GetStartTime()
+	end := p.GetEndTime()
+	if !start.IsZero() && !end.IsZero() {
+		if len(errs) == 0 {
+			errs = append(errs, boundsProblems(p)...)
+		}
+		if !sameLocation(start, end) {
+			errs = append(errs, ErrMismatchedLocations)
+		}
+	}
+	if p.GetIdentifier() == "" {
+		errs = append(errs, ErrEmptyIdentifier)
+	}
+	return errs
+}
+
+// boundsProblems returns ErrEndAfterStart if p, which must have a start and
+// an end time, starts after it ends, or ErrZeroLength if it contains no
+// instant.
+func boundsProblems(p Period) []error {
+	switch {
+	case p.GetStartTime().After(p.GetEndTime()):
+		return []error{ErrEndAfterStart}
+	case !startOf(p).Before(endOf(p)):
+		return []error{ErrZeroLength}
+	}
+	return nil
+}
+
+// sameLocation reports whether a and b are in the same location. Only two
+// different named locations, such as Europe/Berlin and America/New_York,
+// are told apart. Parsing a numeric offset yields an unnamed fixed zone, or
+// Local or UTC when the offset happens to match them, so none of these
+// records where a time was given: a period spanning a daylight saving
+// transition, such as from +02:00 to +01:00, or mixing Z and +00:00, is in
+// the same location as far as it can tell.
+func sameLocation(a, b time.Time) bool {
+	if a.Location() == b.Location() {
+		return true
+	}
+	nameA, nameB := locationName(a), locationName(b)
+	return nameA == "" || nameB == "" || nameA == nameB
+}
+
+// locationName returns the name of t's location, or the empty string if it
+// may stand for any location sharing the offset of t.
+func locationName(t time.Time) string {
+	if loc := t.Location(); loc != time.UTC && loc != time.Local {
+		return loc.String()
+	}
+	return ""
+}
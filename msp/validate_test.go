@@ -0,0 +1,263 @@
+package msp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	// use a static timestamp to make sure tests don't fail on slower systems or during a process pause
+	now := time.Now()
+	testCases := []struct {
+		testID  string
+		periods []Period
+		errs    []error
+		indices []int
+	}{
+		{
+			testID:  "No periods",
+			periods: []Period{},
+		},
+		{
+			testID: "Valid periods",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now,
+					EndTime:    now.Add(time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now,
+					Identifier: "open",
+				},
+			},
+		},
+		{
+			testID: "Inverted bounds",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now,
+					EndTime:    now.Add(time.Minute),
+					Identifier: "A",
+				},
+				TimeWindow{
+					StartTime:  now.Add(time.Minute),
+					EndTime:    now,
+					Identifier: "B",
+				},
+			},
+			errs:    []error{ErrEndAfterStart},
+			indices: []int{1},
+		},
+		{
+			testID: "Zero length",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now,
+					EndTime:    now,
+					Identifier: "A",
+				},
+			},
+			errs:    []error{ErrZeroLength},
+			indices: []int{0},
+		},
+		{
+			testID: "Closed instant",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now,
+					EndTime:    now,
+					Identifier: "A",
+					Bounds:     Closed,
+				},
+			},
+		},
+		{
+			testID: "Open period without instants",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now,
+					EndTime:    now.Add(time.Nanosecond),
+					Identifier: "A",
+					Bounds:     Open,
+				},
+			},
+			errs:    []error{ErrZeroLength},
+			indices: []int{0},
+		},
+		{
+			testID: "Empty identifier",
+			periods: []Period{
+				TimeWindow{
+					StartTime: now,
+					EndTime:   now.Add(time.Minute),
+				},
+			},
+			errs:    []error{ErrEmptyIdentifier},
+			indices: []int{0},
+		},
+		{
+			testID: "Mismatched locations",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.In(time.FixedZone("CET", 60*60)),
+					EndTime:    now.Add(time.Minute).In(time.FixedZone("EST", -5*60*60)),
+					Identifier: "A",
+				},
+			},
+			errs:    []error{ErrMismatchedLocations},
+			indices: []int{0},
+		},
+		{
+			testID: "Numeric offsets across a DST change",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  parseTime(t, "2024-10-26T00:00:00+02:00"),
+					EndTime:    parseTime(t, "2024-10-28T00:00:00+01:00"),
+					Identifier: "A",
+				},
+			},
+		},
+		{
+			testID: "Z and +00:00",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  parseTime(t, "2024-01-01T00:00:00Z"),
+					EndTime:    parseTime(t, "2024-02-01T00:00:00+00:00"),
+					Identifier: "A",
+				},
+			},
+		},
+		{
+			testID: "Local and a named zone",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  now.Local(),
+					EndTime:    now.Add(time.Minute).In(time.FixedZone("EST", -5*60*60)),
+					Identifier: "A",
+				},
+			},
+		},
+		{
+			testID: "Recurring with zero duration",
+			periods: []Period{
+				RecurringPeriod{
+					StartTime:  now,
+					Identifier: "A",
+					Recurrence: Recurrence{Frequency: Daily},
+				},
+				RecurringPeriod{
+					StartTime:  now,
+					Identifier: "B",
+					Recurrence: Recurrence{Frequency: Daily, Count: 3},
+				},
+			},
+			errs:    []error{ErrZeroLength, ErrZeroLength},
+			indices: []int{0, 1},
+		},
+		{
+			testID: "Recurring with negative duration",
+			periods: []Period{
+				RecurringPeriod{
+					StartTime:  now,
+					Duration:   -time.Hour,
+					Identifier: "A",
+					Recurrence: Recurrence{Frequency: Daily},
+				},
+				RecurringPeriod{
+					StartTime:  now,
+					Duration:   -time.Hour,
+					Identifier: "B",
+					Recurrence: Recurrence{Frequency: Daily, Count: 1},
+				},
+			},
+			errs:    []error{ErrEndAfterStart, ErrEndAfterStart},
+			indices: []int{0, 1},
+		},
+		{
+			testID: "Recurring closed instants",
+			periods: []Period{
+				RecurringPeriod{
+					StartTime:  now,
+					Identifier: "A",
+					Bounds:     Closed,
+					Recurrence: Recurrence{Frequency: Daily},
+				},
+			},
+		},
+		{
+			testID: "Numeric offset and UTC",
+			periods: []Period{
+				TimeWindow{
+					StartTime:  parseTime(t, "2024-06-01T00:00:00Z"),
+					EndTime:    parseTime(t, "2024-06-02T00:00:00+02:00"),
+					Identifier: "A",
+				},
+			},
+		},
+		{
+			testID: "Several problems",
+			periods: []Period{
+				TimeWindow{
+					StartTime: now.Add(time.Minute),
+					EndTime:   now,
+				},
+				TimeWindow{
+					StartTime:  now,
+					EndTime:    now,
+					Identifier: "C",
+				},
+			},
+			errs:    []error{ErrEndAfterStart, ErrEmptyIdentifier, ErrZeroLength},
+			indices: []int{0, 0, 1},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			err := Validate(tc.periods...)
+			if len(tc.errs) == 0 {
+				if err != nil {
+					t.Errorf("Error '%v' does not match expected nil", err)
+				}
+				return
+			}
+			joined, ok := err.(interface{ Unwrap() []error })
+			if !ok {
+				t.Fatalf("Expected joined errors but got '%v'", err)
+			}
+			errs := joined.Unwrap()
+			if len(errs) != len(tc.errs) {
+				t.Fatalf("Expected %d errors but got %d: %v", len(tc.errs), len(errs), err)
+			}
+			for i, e := range errs {
+				var v *ValidationError
+				if !errors.As(e, &v) {
+					t.Fatalf("Error '%v' is not a ValidationError", e)
+				}
+				if !errors.Is(e, tc.errs[i]) {
+					t.Errorf("Error '%v' does not match expected '%v'", e, tc.errs[i])
+				}
+				if v.Index != tc.indices[i] {
+					t.Errorf("Index %d does not match expected %d", v.Index, tc.indices[i])
+				}
+				if v.Identifier != tc.periods[v.Index].GetIdentifier() {
+					t.Errorf("Identifier '%s' does not match expected '%s'", v.Identifier, tc.periods[v.Index].GetIdentifier())
+				}
+				if !errors.Is(err, tc.errs[i]) {
+					t.Errorf("Joined error does not wrap '%v'", tc.errs[i])
+				}
+			}
+		})
+	}
+}
+
+// parseTime parses an RFC 3339 timestamp or fails the test.
+func parseTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	ts, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts
+}
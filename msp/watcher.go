@@ -0,0 +1,167 @@
+package msp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// watchHorizon is how far ahead a Watcher looks for the next changeover.
+// When none is found it checks again once the horizon has passed, so
+// recurring periods without end are handled without expanding them
+// indefinitely.
+const watchHorizon = 24 * time.Hour
+
+// Changeover is a change of the most specific period. From and To are the
+// identifiers before and after At; an empty identifier means no period was
+// or is active.
+type Changeover struct {
+	At   time.Time
+	From string
+	To   string
+}
+
+// Watcher reports changeovers of the most specific period as they happen
+// according to a Clock. The set of periods may be replaced while it runs.
+type Watcher struct {
+	clock  Clock
+	policy Policy
+
+	mu      sync.Mutex
+	periods []Period
+	// replaced is signalled by SetPeriods to wake up a running watcher.
+	replaced chan struct{}
+}
+
+// NewWatcher returns a Watcher for periods ranked under DefaultPolicy. A
+// nil clock means RealClock.
+func NewWatcher(clock Clock, periods ...Period) *Watcher {
+	return DefaultPolicy.NewWatcher(clock, periods...)
+}
+
+// NewWatcher returns a Watcher for periods ranked under the policy. A nil
+// clock means RealClock.
+func (p Policy) NewWatcher(clock Clock, periods ...Period) *Watcher {
+	if clock == nil {
+		clock = RealClock{}
+	}
+	return &Watcher{
+		clock:    clock,
+		policy:   p,
+		periods:  periods,
+		replaced: make(chan struct{}, 1),
+	}
+}
+
+// SetPeriods replaces the watched periods. If the most specific period
+// changes as a result, a running watcher reports a changeover at the
+// current time.
+func (w *Watcher) SetPeriods(periods ...Period) {
+	w.mu.Lock()
+	w.periods = periods
+	w.mu.Unlock()
+	select {
+	case w.replaced <- struct{}{}:
+	default:
+	}
+}
+
+// Run calls fn for every changeover, in order, until ctx is done, and then
+// returns ctx.Err(). Changeovers are reported at the time they occur rather
+// than when the watcher wakes up, so a late wakeup does not skew At.
+func (w *Watcher) Run(ctx context.Context, fn func(Changeover)) error {
+	last := w.clock.Now()
+	current := w.identifierAt(last)
+	// Clocks cannot stop a timer, so the pending one is kept until it fires
+	// and only replaced when the deadline moves closer. Waking up before the
+	// deadline is harmless.
+	var timer <-chan time.Time
+	var timerAt time.Time
+	for {
+		deadline := last.Add(watchHorizon)
+		if next := w.changeovers(last, deadline); len(next) > 0 {
+			deadline = next[0].At
+		}
+		if timer == nil || deadline.Before(timerAt) {
+			timer, timerAt = w.clock.After(deadline.Sub(w.clock.Now())), deadline
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.replaced:
+			now := w.clock.Now()
+			if id := w.identifierAt(now); id != current {
+				fn(Changeover{At: now, From: current, To: id})
+				current = id
+			}
+			last = now
+		case <-timer:
+			timer = nil
+			now := w.clock.Now()
+			for _, c := range w.changeovers(last, now) {
+				if c.To == current {
+					continue
+				}
+				c.From = current
+				fn(c)
+				current = c.To
+			}
+			last = now
+		}
+	}
+}
+
+// Watch runs the watcher in a new goroutine and returns a channel delivering
+// its changeovers. The channel is closed once ctx is done.
+func (w *Watcher) Watch(ctx context.Context) <-chan Changeover {
+	events := make(chan Changeover)
+	go func() {
+		defer close(events)
+		w.Run(ctx, func(c Changeover) {
+			select {
+			case events <- c:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	return events
+}
+
+// identifierAt returns the most specific identifier at ts, or the empty
+// string if no period is active.
+func (w *Watcher) identifierAt(ts time.Time) string {
+	id, _ := w.policy.MostSpecificPeriod(ts, w.snapshot()...)
+	return id
+}
+
+// changeovers returns the changeovers in (from, to] of the current periods.
+func (w *Watcher) changeovers(from, to time.Time) []Changeover {
+	return w.policy.changeoversBetween(from, to, w.snapshot())
+}
+
+// snapshot returns the current periods.
+func (w *Watcher) snapshot() []Period {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.periods
+}
+
+// changeoversBetween returns the changeovers in (from, to], in order.
+// Recurring periods are expanded within the range.
+func (p Policy) changeoversBetween(from, to time.Time, periods []Period) []Changeover {
+	r := p.newResolver(expandBetween(from, to.Add(time.Nanosecond), periods))
+	previous := r.lookup(from).identifier()
+	var out []Changeover
+	for i, b := range r.bounds {
+		if !b.After(from) || b.After(to) {
+			continue
+		}
+		id := r.winners[i].identifier()
+		if id == previous {
+			continue
+		}
+		out = append(out, Changeover{At: b, From: previous, To: id})
+		previous = id
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package msp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func expectChangeover(t *testing.T, events <-chan Changeover, expected Changeover) {
+	t.Helper()
+	select {
+	case c := <-events:
+		if !c.At.Equal(expected.At) || c.From != expected.From || c.To != expected.To {
+			t.Errorf("Expected changeover %+v but got %+v", expected, c)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for changeover %+v", expected)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 8, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(now)
+	w := NewWatcher(clock,
+		TimeWindow{
+			StartTime:  now.Add(-time.Hour),
+			EndTime:    now.Add(10 * time.Hour),
+			Identifier: "day",
+		},
+		TimeWindow{
+			StartTime:  now.Add(time.Hour),
+			EndTime:    now.Add(2 * time.Hour),
+			Identifier: "morning",
+		},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	events := w.Watch(ctx)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	expectChangeover(t, events, Changeover{At: now.Add(time.Hour), From: "day", To: "morning"})
+
+	// a late wakeup still reports both changeovers at the time they happened
+	clock.BlockUntil(1)
+	clock.Advance(10 * time.Hour)
+	expectChangeover(t, events, Changeover{At: now.Add(2 * time.Hour), From: "morning", To: "day"})
+	expectChangeover(t, events, Changeover{At: now.Add(10 * time.Hour), From: "day", To: ""})
+
+	// replacing the periods reports the change immediately
+	clock.BlockUntil(1)
+	w.SetPeriods(TimeWindow{
+		StartTime:  now,
+		EndTime:    now.Add(20 * time.Hour),
+		Identifier: "extended",
+	})
+	expectChangeover(t, events, Changeover{At: now.Add(11 * time.Hour), From: "", To: "extended"})
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatcherRecurring(t *testing.T) {
+	start := time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start.AddDate(1, 0, 0).Add(-time.Hour))
+	w := NewWatcher(clock, RecurringPeriod{
+		StartTime:  start,
+		Duration:   time.Hour,
+		Identifier: "standup",
+		Recurrence: Recurrence{Frequency: Daily},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var got []Changeover
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx, func(c Changeover) {
+			got = append(got, c)
+			if len(got) == 2 {
+				cancel()
+			}
+		})
+	}()
+	// both changeovers of the day's occurrence happen before the watcher
+	// wakes up
+	clock.BlockUntil(1)
+	clock.Advance(2 * time.Hour)
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run returned %v, expected %v", err, context.Canceled)
+	}
+	expected := []Changeover{
+		{At: clock.Now().Add(-time.Hour), From: "", To: "standup"},
+		{At: clock.Now(), From: "standup", To: ""},
+	}
+	if !slicesEqual(got, expected) {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+}
+
+func TestWatcherKeepsPendingTimer(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 8, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(now)
+	window := func(id string) Period {
+		return TimeWindow{StartTime: now.Add(-time.Hour), EndTime: now.Add(10 * time.Hour), Identifier: id}
+	}
+	w := NewWatcher(clock, window("A"))
+	ctx, cancel := context.WithCancel(context.Background())
+	events := w.Watch(ctx)
+
+	// replacing the periods without moving the next changeover reuses the
+	// pending timer
+	clock.BlockUntil(1)
+	w.SetPeriods(window("B"))
+	expectChangeover(t, events, Changeover{At: now, From: "A", To: "B"})
+	w.SetPeriods(window("A"))
+	expectChangeover(t, events, Changeover{At: now, From: "B", To: "A"})
+	clock.mu.Lock()
+	waiters := len(clock.waiters)
+	clock.mu.Unlock()
+	if waiters != 1 {
+		t.Errorf("Expected 1 pending timer but got %d", waiters)
+	}
+
+	clock.Advance(10 * time.Hour)
+	expectChangeover(t, events, Changeover{At: now.Add(10 * time.Hour), From: "A", To: ""})
+
+	cancel()
+	for range events {
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/taigrr/most-specific-period/msp"
+)
+
+// outputFormats lists the values accepted by -output.
+var outputFormats = []string{"text", "json", "csv", "table"}
+
+// Exit codes distinguishing the ways the CLI can fail.
+const (
+	exitOK = 0
+	// exitNoPeriod means the input was fine but no period contains the
+	// queried timestamp.
+	exitNoPeriod = 1
+	// exitBadInput means the flags or the periods could not be parsed.
+	exitBadInput = 2
+)
+
+// result is the outcome of a command. It is written as JSON by marshalling
+// the value itself, and as CSV or an aligned table from its rows.
+type result interface {
+	// text writes the result for humans.
+	text(w io.Writer)
+	// rows returns a header and the rows below it.
+	rows() (header []string, rows [][]string)
+}
+
+// writeResult writes r to w in the given format.
+func writeResult(w io.Writer, format string, r result) error {
+	switch format {
+	case "text":
+		r.text(w)
+		return nil
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "csv":
+		header, rows := r.rows()
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		cw.WriteAll(rows)
+		return cw.Error()
+	case "table":
+		header, rows := r.rows()
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// periodRecord is a period as written by the structured output formats. A
+// nil time leaves the period unbounded on that side.
+type periodRecord struct {
+	Identifier string     `json:"identifier"`
+	Start      *time.Time `json:"start"`
+	End        *time.Time `json:"end"`
+}
+
+// toRecord converts p for output.
+func toRecord(p msp.Period) periodRecord {
+	return periodRecord{
+		Identifier: p.GetIdentifier(),
+		Start:      optionalTime(p.GetStartTime()),
+		End:        optionalTime(p.GetEndTime()),
+	}
+}
+
+// toRecords converts periods for output.
+func toRecords(periods []msp.Period) []periodRecord {
+	records := []periodRecord{}
+	for _, p := range periods {
+		records = append(records, toRecord(p))
+	}
+	return records
+}
+
+// row returns the record as identifier, start and end columns.
+func (p periodRecord) row() []string {
+	return []string{p.Identifier, formatTime(p.Start), formatTime(p.End)}
+}
+
+// optionalTime returns nil for the zero time and &t otherwise.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// formatTime formats an optional time for the text based formats, leaving
+// unbounded times empty.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// report is the structured result of running without a command.
+type report struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Winner is nil if no period contains Timestamp.
+	Winner      *periodRecord  `json:"winner"`
+	Timeline    []periodRecord `json:"timeline"`
+	Changeovers []time.Time    `json:"changeovers"`
+}
+
+// newReport resolves periods at ts.
+func newReport(ts time.Time, periods []msp.Period) report {
+	r := report{
+		Timestamp:   ts,
+		Timeline:    toRecords(msp.GenerateTimeline(periods...)),
+		Changeovers: append([]time.Time{}, msp.GetChangeOvers(periods...)...),
+	}
+	if winner, err := msp.MostSpecific(ts, periods...); err == nil {
+		record := toRecord(winner)
+		r.Winner = &record
+	}
+	return r
+}
+
+// writeReport writes the report of periods at ts to w in the given format.
+// It returns the exit code.
+func writeReport(w io.Writer, format string, ts time.Time, periods []msp.Period) int {
+	r := newReport(ts, periods)
+	if err := writeResult(w, format, r); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return exitBadInput
+	}
+	if r.Winner == nil {
+		return exitNoPeriod
+	}
+	return exitOK
+}
+
+// text writes the timeline followed by the winner.
+func (r report) text(w io.Writer) {
+	for _, p := range r.Timeline {
+		fmt.Fprintln(w, strings.Join(p.row(), "\t"))
+	}
+	if r.Winner != nil {
+		fmt.Fprintln(w, r.Winner.Identifier)
+	}
+}
+
+// rows returns one row per record, the first column telling what it
+// describes.
+func (r report) rows() ([]string, [][]string) {
+	rows := [][]string{{"timestamp", "", r.Timestamp.Format(time.RFC3339Nano), ""}}
+	if r.Winner != nil {
+		rows = append(rows, append([]string{"winner"}, r.Winner.row()...))
+	}
+	for _, p := range r.Timeline {
+		rows = append(rows, append([]string{"timeline"}, p.row()...))
+	}
+	for _, c := range r.Changeovers {
+		rows = append(rows, []string{"changeover", "", c.Format(time.RFC3339Nano), ""})
+	}
+	return []string{"record", "identifier", "start", "end"}, rows
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taigrr/most-specific-period/msp"
+)
+
+// fixture is the input shared by the CLI tests: a summer with a more
+// specific June, and a gap before the autumn.
+const fixture = `summer
+2024-06-01T00:00:00Z
+2024-09-01T00:00:00Z
+june
+2024-06-01T00:00:00Z
+2024-07-01T00:00:00Z
+autumn
+2024-09-15T00:00:00Z
+2024-12-01T00:00:00Z
+`
+
+// fixturePeriods returns the periods of fixture.
+func fixturePeriods(t *testing.T) []msp.Period {
+	t.Helper()
+	periods, err := readLines(strings.NewReader(fixture), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return periods
+}
+
+// runCLI runs the named command on input with args and returns its output
+// and exit code.
+func runCLI(t *testing.T, name, input string, args ...string) (string, int) {
+	t.Helper()
+	i := slices.IndexFunc(commands, func(c command) bool { return c.name == name })
+	if i < 0 {
+		t.Fatalf("unknown command %q", name)
+	}
+	path := filepath.Join(t.TempDir(), "input")
+	if err := os.WriteFile(path, []byte(input), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stdin, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	var stdout bytes.Buffer
+	code := runCommand(commands[i], args, stdin, &stdout)
+	return stdout.String(), code
+}
+
+func TestWriteReport(t *testing.T) {
+	periods := fixturePeriods(t)
+	testCases := []struct {
+		testID string
+		format string
+		ts     time.Time
+		code   int
+		check  func(t *testing.T, out string)
+	}{
+		{
+			testID: "JSON",
+			format: "json",
+			ts:     time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC),
+			code:   exitOK,
+			check: func(t *testing.T, out string) {
+				var doc map[string]any
+				if err := json.Unmarshal([]byte(out), &doc); err != nil {
+					t.Fatalf("Invalid JSON: %v", err)
+				}
+				keys := []string{}
+				for k := range doc {
+					keys = append(keys, k)
+				}
+				slices.Sort(keys)
+				if expected := []string{"changeovers", "timeline", "timestamp", "winner"}; !reflect.DeepEqual(keys, expected) {
+					t.Errorf("Keys %v do not match expected %v", keys, expected)
+				}
+				winner, _ := doc["winner"].(map[string]any)
+				if winner["identifier"] != "june" || winner["start"] != "2024-06-01T00:00:00Z" || winner["end"] != "2024-07-01T00:00:00Z" {
+					t.Errorf("Winner %v does not match expected june", doc["winner"])
+				}
+				if timeline, _ := doc["timeline"].([]any); len(timeline) != 3 {
+					t.Errorf("Expected 3 timeline entries but got %v", doc["timeline"])
+				}
+				if changeovers, _ := doc["changeovers"].([]any); len(changeovers) != 5 {
+					t.Errorf("Expected 5 changeovers but got %v", doc["changeovers"])
+				}
+			},
+		},
+		{
+			testID: "JSON without winner",
+			format: "json",
+			ts:     time.Date(2024, time.September, 10, 0, 0, 0, 0, time.UTC),
+			code:   exitNoPeriod,
+			check: func(t *testing.T, out string) {
+				var doc map[string]any
+				if err := json.Unmarshal([]byte(out), &doc); err != nil {
+					t.Fatalf("Invalid JSON: %v", err)
+				}
+				if winner, ok := doc["winner"]; !ok || winner != nil {
+					t.Errorf("Winner %v does not match expected null", winner)
+				}
+			},
+		},
+		{
+			testID: "CSV",
+			format: "csv",
+			ts:     time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC),
+			code:   exitOK,
+			check: func(t *testing.T, out string) {
+				rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+				if err != nil {
+					t.Fatalf("Invalid CSV: %v", err)
+				}
+				expected := [][]string{
+					{"record", "identifier", "start", "end"},
+					{"timestamp", "", "2024-06-15T00:00:00Z", ""},
+					{"winner", "june", "2024-06-01T00:00:00Z", "2024-07-01T00:00:00Z"},
+					{"timeline", "june", "2024-06-01T00:00:00Z", "2024-07-01T00:00:00Z"},
+					{"timeline", "summer", "2024-07-01T00:00:00Z", "2024-09-01T00:00:00Z"},
+					{"timeline", "autumn", "2024-09-15T00:00:00Z", "2024-12-01T00:00:00Z"},
+					{"changeover", "", "2024-06-01T00:00:00Z", ""},
+					{"changeover", "", "2024-07-01T00:00:00Z", ""},
+					{"changeover", "", "2024-09-01T00:00:00Z", ""},
+					{"changeover", "", "2024-09-15T00:00:00Z", ""},
+					{"changeover", "", "2024-12-01T00:00:00Z", ""},
+				}
+				if !reflect.DeepEqual(rows, expected) {
+					t.Errorf("Rows %v do not match expected %v", rows, expected)
+				}
+			},
+		},
+		{
+			testID: "CSV without winner",
+			format: "csv",
+			ts:     time.Date(2024, time.September, 10, 0, 0, 0, 0, time.UTC),
+			code:   exitNoPeriod,
+			check: func(t *testing.T, out string) {
+				rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+				if err != nil {
+					t.Fatalf("Invalid CSV: %v", err)
+				}
+				for _, row := range rows {
+					if row[0] == "winner" {
+						t.Errorf("Unexpected winner row %v", row)
+					}
+				}
+			},
+		},
+		{
+			testID: "Table",
+			format: "table",
+			ts:     time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC),
+			code:   exitOK,
+			check: func(t *testing.T, out string) {
+				lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+				if len(lines) != 11 {
+					t.Fatalf("Expected 11 lines but got %d:\n%s", len(lines), out)
+				}
+				if fields := strings.Fields(lines[0]); !reflect.DeepEqual(fields, []string{"RECORD", "IDENTIFIER", "START", "END"}) {
+					t.Errorf("Header %v does not match expected", fields)
+				}
+				if fields := strings.Fields(lines[2]); !reflect.DeepEqual(fields, []string{"winner", "june", "2024-06-01T00:00:00Z", "2024-07-01T00:00:00Z"}) {
+					t.Errorf("Winner row %v does not match expected", fields)
+				}
+				// columns are aligned
+				column := strings.Index(lines[0], "IDENTIFIER")
+				for _, line := range lines[1:] {
+					if line[column-1] != ' ' || line[column-2] != ' ' {
+						t.Errorf("Line %q is not aligned with the header", line)
+					}
+				}
+			},
+		},
+		{
+			testID: "Text",
+			format: "text",
+			ts:     time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC),
+			code:   exitOK,
+			check: func(t *testing.T, out string) {
+				expected := "june\t2024-06-01T00:00:00Z\t2024-07-01T00:00:00Z\n" +
+					"summer\t2024-07-01T00:00:00Z\t2024-09-01T00:00:00Z\n" +
+					"autumn\t2024-09-15T00:00:00Z\t2024-12-01T00:00:00Z\n" +
+					"june\n"
+				if out != expected {
+					t.Errorf("Output %q does not match expected %q", out, expected)
+				}
+			},
+		},
+		{
+			testID: "Unknown format",
+			format: "xml",
+			ts:     time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC),
+			code:   exitBadInput,
+			check: func(t *testing.T, out string) {
+				if out != "" {
+					t.Errorf("Unexpected output %q", out)
+				}
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			var b bytes.Buffer
+			if code := writeReport(&b, tc.format, tc.ts, periods); code != tc.code {
+				t.Errorf("Exit code %d does not match expected %d", code, tc.code)
+			}
+			tc.check(t, b.String())
+		})
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	testCases := []struct {
+		testID  string
+		command string
+		input   string
+		args    []string
+		code    int
+	}{
+		{
+			testID:  "Period found",
+			command: "resolve",
+			input:   fixture,
+			args:    []string{"-d", "2024-06-15T00:00:00Z"},
+			code:    exitOK,
+		},
+		{
+			testID:  "No period",
+			command: "resolve",
+			input:   fixture,
+			args:    []string{"-d", "2024-09-10T00:00:00Z"},
+			code:    exitNoPeriod,
+		},
+		{
+			testID:  "No period is not an error in structured output",
+			command: "valid",
+			input:   fixture,
+			args:    []string{"-d", "2024-09-10T00:00:00Z", "-output", "json"},
+			code:    exitNoPeriod,
+		},
+		{
+			testID:  "No next changeover",
+			command: "next",
+			input:   fixture,
+			args:    []string{"-d", "2025-01-01T00:00:00Z"},
+			code:    exitNoPeriod,
+		},
+		{
+			testID:  "Invalid timestamp in the input",
+			command: "resolve",
+			input:   "a\nyesterday\n2024-06-01T00:00:00Z\n",
+			code:    exitBadInput,
+		},
+		{
+			testID:  "Incomplete input",
+			command: "resolve",
+			input:   "a\n2024-06-01T00:00:00Z\n",
+			code:    exitBadInput,
+		},
+		{
+			testID:  "Invalid -d",
+			command: "resolve",
+			input:   fixture,
+			args:    []string{"-d", "tomorrow"},
+			code:    exitBadInput,
+		},
+		{
+			testID:  "Unknown output format",
+			command: "resolve",
+			input:   fixture,
+			args:    []string{"-output", "xml"},
+			code:    exitBadInput,
+		},
+		{
+			testID:  "Unknown input format",
+			command: "resolve",
+			input:   fixture,
+			args:    []string{"-input-format", "xml"},
+			code:    exitBadInput,
+		},
+		{
+			testID:  "Unknown flag",
+			command: "resolve",
+			input:   fixture,
+			args:    []string{"-verbose"},
+			code:    exitBadInput,
+		},
+		{
+			testID:  "Help",
+			command: "resolve",
+			input:   fixture,
+			args:    []string{"-h"},
+			code:    exitOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			if _, code := runCLI(t, tc.command, tc.input, tc.args...); code != tc.code {
+				t.Errorf("Exit code %d does not match expected %d", code, tc.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/taigrr/most-specific-period/msp"
+)
+
+// maxCacheAge caps how long answers about the current time may be cached
+// when nothing changes sooner.
+const maxCacheAge = 24 * time.Hour
+
+// serveFlags are the flags of the serve command.
+type serveFlags struct {
+	addr string
+}
+
+var serveOptions serveFlags
+
+// register adds the serve flags to fs.
+func (f *serveFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.addr, "addr", "localhost:8080", "address to listen on")
+}
+
+// serve answers queries about the periods over HTTP until the server fails.
+// Answers about the current time follow the real clock, so -d is rejected.
+func serve(clock msp.Clock, periods []msp.Period) (result, int) {
+	if _, ok := clock.(msp.RealClock); !ok {
+		fmt.Fprintln(os.Stderr, "ERROR: serve answers about the current time and does not take -d; pass at or after to each request instead")
+		return nil, exitBadInput
+	}
+	log.Printf("serving %d periods on %s", len(periods), serveOptions.addr)
+	err := http.ListenAndServe(serveOptions.addr, newServer(clock, periods))
+	fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+	return nil, exitBadInput
+}
+
+// server is the HTTP API over a fixed set of periods. Every endpoint
+// answers with a JSON object, and with {"error": "..."} on failure.
+type server struct {
+	clock   msp.Clock
+	periods []msp.Period
+}
+
+// newServer returns the handler serving periods.
+func newServer(clock msp.Clock, periods []msp.Period) http.Handler {
+	s := &server{clock: clock, periods: periods}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /resolve", s.resolve)
+	mux.HandleFunc("POST /resolve", s.resolve)
+	mux.HandleFunc("GET /timeline", s.timeline)
+	mux.HandleFunc("GET /changeovers", s.changeovers)
+	mux.HandleFunc("GET /changeovers/next", s.next)
+	mux.HandleFunc("POST /changeovers/next", s.next)
+	mux.HandleFunc("GET /valid", s.valid)
+	mux.HandleFunc("POST /valid", s.valid)
+	return mux
+}
+
+// resolve answers with the most specific period at the requested time.
+func (s *server) resolve(w http.ResponseWriter, r *http.Request) {
+	ts, current, err := s.timestamp(r, "at")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if current {
+		s.cacheUntilNextChangeover(w, ts)
+	}
+	winner, err := msp.MostSpecific(ts, s.periods...)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	record := toRecord(winner)
+	writeJSON(w, http.StatusOK, resolveResult{Timestamp: ts, Winner: &record})
+}
+
+// timeline answers with GenerateTimeline.
+func (s *server) timeline(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"timeline": toRecords(msp.GenerateTimeline(s.periods...)),
+	})
+}
+
+// changeovers answers with GetChangeOvers.
+func (s *server) changeovers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"changeovers": append([]time.Time{}, msp.GetChangeOvers(s.periods...)...),
+	})
+}
+
+// next answers with the first changeover after the requested time.
+func (s *server) next(w http.ResponseWriter, r *http.Request) {
+	ts, current, err := s.timestamp(r, "after")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if current {
+		s.cacheUntilNextChangeover(w, ts)
+	}
+	next, err := msp.GetNextChangeOver(ts, s.periods...)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"timestamp": ts, "next": next})
+}
+
+// valid answers with the periods containing the requested time.
+func (s *server) valid(w http.ResponseWriter, r *http.Request) {
+	ts, current, err := s.timestamp(r, "at")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if current {
+		s.cacheUntilNextBoundary(w, ts)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"timestamp": ts,
+		"periods":   toRecords(msp.ValidTimePeriods(ts, s.periods...)),
+	})
+}
+
+// timestamp returns the RFC 3339 time named name, taken from the query
+// string of a GET request or the JSON body of a POST request. If it is
+// missing, the current time is returned and current is true.
+func (s *server) timestamp(r *http.Request, name string) (ts time.Time, current bool, err error) {
+	value := r.URL.Query().Get(name)
+	if r.Method == http.MethodPost {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return ts, false, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		value = body[name]
+	}
+	if value == "" {
+		return s.clock.Now(), true, nil
+	}
+	ts, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return ts, false, fmt.Errorf("invalid timestamp %q in %q, expected RFC 3339", value, name)
+	}
+	return ts, false, nil
+}
+
+// cacheUntilNextChangeover lets clients cache an answer about the current
+// time until the most specific period next changes, including to another
+// period or occurrence with the same identifier, whose start and end differ.
+// Recurring periods without end are taken into account too.
+func (s *server) cacheUntilNextChangeover(w http.ResponseWriter, now time.Time) {
+	expires := now.Add(maxCacheAge)
+	// segments end wherever the winning period instance changes
+	if segments := msp.TimelineBetween(now, expires, s.periods...); len(segments) > 1 {
+		expires = segments[1].StartTime
+	}
+	cacheUntil(w, now, expires)
+}
+
+// cacheUntilNextBoundary lets clients cache the periods containing the
+// current time until any period next starts or ends, which may happen while
+// the most specific period stays the same.
+func (s *server) cacheUntilNextBoundary(w http.ResponseWriter, now time.Time) {
+	expires := now.Add(maxCacheAge)
+	for _, p := range msp.Expand(now, expires, s.periods...) {
+		for _, b := range []time.Time{p.GetStartTime(), p.GetEndTime()} {
+			if b.After(now) && b.Before(expires) {
+				expires = b
+			}
+		}
+	}
+	cacheUntil(w, now, expires)
+}
+
+// cacheUntil sets the caching headers of an answer given at now that stays
+// valid until expires. Both are rounded down to whole seconds, so that the
+// answer is never cached past expires.
+func cacheUntil(w http.ResponseWriter, now, expires time.Time) {
+	age := int(expires.Sub(now) / time.Second)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(age))
+	w.Header().Set("Expires", expires.UTC().Format(http.TimeFormat))
+}
+
+// statusFor maps errors of the msp package to HTTP status codes.
+func statusFor(err error) int {
+	var v *msp.ValidationError
+	switch {
+	case errors.Is(err, msp.ErrNoValidPeriods), errors.Is(err, msp.ErrNoNextChangeover):
+		return http.StatusNotFound
+	case errors.As(err, &v), errors.Is(err, msp.ErrEndAfterStart):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// writeJSON writes v as the response body.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as the response body.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
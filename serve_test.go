@@ -0,0 +1,177 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taigrr/most-specific-period/msp"
+)
+
+func TestServer(t *testing.T) {
+	start := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	hour := func(h int) time.Time {
+		return start.Add(time.Duration(h) * time.Hour)
+	}
+	periods := []msp.Period{
+		msp.TimeWindow{StartTime: hour(2), EndTime: hour(5), Identifier: "inner"},
+		msp.TimeWindow{StartTime: hour(0), EndTime: hour(4), Identifier: "medium"},
+		msp.TimeWindow{StartTime: hour(0), EndTime: hour(20), Identifier: "big"},
+		msp.TimeWindow{StartTime: hour(72), EndTime: hour(74), Identifier: "promo"},
+		msp.TimeWindow{StartTime: hour(74), EndTime: hour(76), Identifier: "promo"},
+	}
+	testCases := []struct {
+		testID  string
+		method  string
+		target  string
+		body    string
+		now     time.Time
+		status  int
+		expires time.Time
+	}{
+		{
+			testID: "Resolve at",
+			method: http.MethodGet,
+			target: "/resolve?at=2024-06-01T03:00:00Z",
+			now:    hour(3),
+			status: http.StatusOK,
+		},
+		{
+			testID: "Resolve outside all periods",
+			method: http.MethodGet,
+			target: "/resolve?at=2024-06-02T00:00:00Z",
+			now:    hour(3),
+			status: http.StatusNotFound,
+		},
+		{
+			testID: "Resolve invalid timestamp",
+			method: http.MethodGet,
+			target: "/resolve?at=tomorrow",
+			now:    hour(3),
+			status: http.StatusBadRequest,
+		},
+		{
+			testID: "Resolve invalid body",
+			method: http.MethodPost,
+			target: "/resolve",
+			body:   "{",
+			now:    hour(3),
+			status: http.StatusBadRequest,
+		},
+		{
+			testID:  "Resolve now",
+			method:  http.MethodGet,
+			target:  "/resolve",
+			now:     hour(3),
+			status:  http.StatusOK,
+			expires: hour(5),
+		},
+		{
+			testID:  "Resolve now by POST",
+			method:  http.MethodPost,
+			target:  "/resolve",
+			body:    "{}",
+			now:     hour(3),
+			status:  http.StatusOK,
+			expires: hour(5),
+		},
+		{
+			testID:  "Resolve now without period",
+			method:  http.MethodGet,
+			target:  "/resolve",
+			now:     hour(30),
+			status:  http.StatusNotFound,
+			expires: hour(54),
+		},
+		{
+			testID:  "Resolve now expires at the next period with the same identifier",
+			method:  http.MethodGet,
+			target:  "/resolve",
+			now:     hour(73),
+			status:  http.StatusOK,
+			expires: hour(74),
+		},
+		{
+			testID:  "Resolve now is not cached past the changeover",
+			method:  http.MethodGet,
+			target:  "/resolve",
+			now:     hour(5).Add(-500 * time.Millisecond),
+			status:  http.StatusOK,
+			expires: hour(5),
+		},
+		{
+			testID:  "Valid now expires when a losing period ends",
+			method:  http.MethodGet,
+			target:  "/valid",
+			now:     hour(3),
+			status:  http.StatusOK,
+			expires: hour(4),
+		},
+		{
+			testID: "Next after",
+			method: http.MethodGet,
+			target: "/changeovers/next?after=2024-06-01T03:00:00Z",
+			now:    hour(3),
+			status: http.StatusOK,
+		},
+		{
+			testID:  "Next now",
+			method:  http.MethodGet,
+			target:  "/changeovers/next",
+			now:     hour(3),
+			status:  http.StatusOK,
+			expires: hour(5),
+		},
+		{
+			testID: "No next changeover",
+			method: http.MethodGet,
+			target: "/changeovers/next?after=2024-06-05T00:00:00Z",
+			now:    hour(3),
+			status: http.StatusNotFound,
+		},
+		{
+			testID: "Timeline",
+			method: http.MethodGet,
+			target: "/timeline",
+			now:    hour(3),
+			status: http.StatusOK,
+		},
+		{
+			testID: "Unknown method",
+			method: http.MethodDelete,
+			target: "/resolve",
+			now:    hour(3),
+			status: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testID, func(t *testing.T) {
+			handler := newServer(msp.NewFakeClock(tc.now), periods)
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("Status %d does not match expected %d: %s", rec.Code, tc.status, rec.Body)
+			}
+			cacheControl := rec.Header().Get("Cache-Control")
+			expires := rec.Header().Get("Expires")
+			if tc.expires.IsZero() {
+				if cacheControl != "" || expires != "" {
+					t.Errorf("Expected no caching headers, got %q and %q", cacheControl, expires)
+				}
+				return
+			}
+			// whole seconds, rounded down
+			expected := "public, max-age=" + strconv.Itoa(int(tc.expires.Sub(tc.now)/time.Second))
+			if cacheControl != expected {
+				t.Errorf("Cache-Control %q does not match expected %q", cacheControl, expected)
+			}
+			if expected := tc.expires.Format(http.TimeFormat); expires != expected {
+				t.Errorf("Expires %q does not match expected %q", expires, expected)
+			}
+		})
+	}
+}